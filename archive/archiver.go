@@ -0,0 +1,383 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/streamer"
+)
+
+const (
+	//DefaultMaxSegmentBytes is the uncompressed size at which a segment is closed when none is specified
+	DefaultMaxSegmentBytes int64 = 64 << 20
+	//DefaultMaxSegmentAge is the time after which a segment is closed when none is specified
+	DefaultMaxSegmentAge time.Duration = 1 * time.Hour
+	//DefaultPollInterval is the time between two reads once the archiver caught up with the stream when none is specified
+	DefaultPollInterval time.Duration = 1 * time.Second
+	//DefaultHoldInterval is the time between two refreshes of the retention hold when none is specified
+	DefaultHoldInterval time.Duration = 1 * time.Minute
+	//DefaultName is the name of the retention hold when none is specified
+	DefaultName string = "archiver"
+
+	//streamStartID is lower than the ID of any entry
+	streamStartID string        = "0-0"
+	retryWait     time.Duration = 1 * time.Second
+	//readCount is the number of entries read at once
+	readCount int64 = 1000
+)
+
+//Archiver copies the entries of a stream into gzip compressed NDJSON segments.
+//Segments are closed once they grow too big or too old and are then added to the index,
+//which also records the position to resume from.
+//The stream is only read, malformed messages are skipped and left to the consumers.
+//A retention hold keeps the entries which are not archived yet from being trimmed.
+type Archiver struct {
+	//Repo reads the stream, queries without an object type have to read it.
+	Repo    domain.ArchiveRepository
+	Backend Backend
+	Clock   streamer.Clock
+
+	Stream string
+	//Name is the name of the retention hold, archivers of the same stream need different names.
+	Name string
+
+	MaxSegmentBytes int64
+	MaxSegmentAge   time.Duration
+	//PollInterval is the time between two reads once the archiver caught up with the stream.
+	PollInterval time.Duration
+	//HoldInterval is the time between two refreshes of the retention hold.
+	HoldInterval time.Duration
+
+	index    Index
+	position string
+	segment  *segment
+	caughtUp bool
+	heldAt   time.Time
+}
+
+//segment is the segment being written.
+type segment struct {
+	Segment
+	file  Writer
+	gz    *gzip.Writer
+	bytes int64
+}
+
+//Write counts the uncompressed bytes written to the segment.
+func (s *segment) Write(p []byte) (int, error) {
+	n, err := s.gz.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+//Run archives the stream until the context is done, then closes the open segment.
+func (a *Archiver) Run(ctx context.Context) error {
+	err := a.Load(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		err = a.Archive(ctx)
+		wait := time.Duration(0)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Println(err.Error())
+			wait = retryWait
+		case a.caughtUp:
+			wait = a.pollInterval()
+		}
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+	}
+
+	//The open segment is stored even though the context is done.
+	return a.Flush(context.Background())
+}
+
+//Load reads the index, holds the entries after its position and resumes from there.
+//Entries removed from the stream before they were archived are reported.
+func (a *Archiver) Load(ctx context.Context) error {
+	index, err := LoadIndex(ctx, a.Backend, a.Stream)
+
+	if err != nil {
+		return fmt.Errorf("Load: %s", err)
+	}
+
+	a.index = index
+	a.rewind()
+
+	err = a.hold(ctx)
+
+	if err != nil {
+		return fmt.Errorf("Load: %s", err)
+	}
+
+	err = a.checkGap(ctx)
+
+	if err != nil {
+		return fmt.Errorf("Load: %s", err)
+	}
+
+	return nil
+}
+
+//Archive reads the next page of entries into the open segment and
+//closes the segment if it grew too big or too old.
+func (a *Archiver) Archive(ctx context.Context) error {
+	page, err := a.Repo.QueryEntries(ctx, domain.EntryQuery{
+		From:  domain.NextID(a.position),
+		Count: readCount,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Archive: %s", err)
+	}
+
+	for _, e := range page.Entries {
+		err = a.write(ctx, e)
+
+		if err != nil {
+			a.discard()
+			return fmt.Errorf("Archive: %s", err)
+		}
+	}
+
+	//Next follows the last message read, which may be a skipped malformed one.
+	switch {
+	case page.Next != "":
+		a.position = domain.PreviousID(page.Next)
+	case len(page.Entries) > 0:
+		a.position = page.Entries[len(page.Entries)-1].ID
+	}
+
+	a.caughtUp = page.Next == ""
+
+	if a.Clock.Now().Sub(a.heldAt) >= a.holdInterval() {
+		err = a.hold(ctx)
+
+		if err != nil {
+			return fmt.Errorf("Archive: %s", err)
+		}
+	}
+
+	if a.segment == nil {
+		return nil
+	}
+
+	if a.segment.bytes >= a.maxSegmentBytes() || a.Clock.Now().Sub(a.segment.Created) >= a.maxSegmentAge() {
+		return a.Flush(ctx)
+	}
+
+	return nil
+}
+
+//Flush closes the open segment and adds it to the index.
+func (a *Archiver) Flush(ctx context.Context) error {
+	if a.segment == nil {
+		return nil
+	}
+
+	s := a.segment
+	a.segment = nil
+
+	err := s.gz.Close()
+
+	if err != nil {
+		s.file.Abort()
+		a.rewind()
+		return fmt.Errorf("Flush: %s", err)
+	}
+
+	err = s.file.Close()
+
+	if err != nil {
+		a.rewind()
+		return fmt.Errorf("Flush: %s", err)
+	}
+
+	index := a.index
+	index.Position = a.position
+	index.Segments = append(index.Segments[:len(index.Segments):len(index.Segments)], s.Segment)
+
+	err = index.store(ctx, a.Backend)
+
+	if err != nil {
+		//The segment is written again under the same name once its entries are read again.
+		a.rewind()
+		return fmt.Errorf("Flush: %s", err)
+	}
+
+	a.index = index
+	log.Printf("Archived %d entries from %s to %s\n", s.Count, a.Stream, s.Name)
+
+	err = a.hold(ctx)
+
+	if err != nil {
+		//The hold is retried on the next read.
+		a.heldAt = time.Time{}
+		return fmt.Errorf("Flush: %s", err)
+	}
+
+	return nil
+}
+
+//hold keeps the entries from the last archived one onwards in the stream.
+//The last archived entry is kept so a gap can be told apart on resume.
+func (a *Archiver) hold(ctx context.Context) error {
+	ID := a.index.Position
+
+	if ID == "" {
+		ID = streamStartID
+	}
+
+	err := a.Repo.HoldEntries(ctx, a.Stream, a.name(), ID)
+
+	if err != nil {
+		return err
+	}
+
+	a.heldAt = a.Clock.Now()
+
+	return nil
+}
+
+//checkGap logs loudly when the stream starts after the last archived entry,
+//the entries in between were removed before they were archived.
+func (a *Archiver) checkGap(ctx context.Context) error {
+	if a.index.Position == "" {
+		return nil
+	}
+
+	page, err := a.Repo.QueryEntries(ctx, domain.EntryQuery{Count: 1})
+
+	if err != nil {
+		return err
+	}
+
+	if len(page.Entries) == 0 {
+		return nil
+	}
+
+	first := page.Entries[0].ID
+	cmp, err := domain.CompareIDs(first, a.index.Position)
+
+	if err == nil && cmp > 0 {
+		log.Printf("WARNING: stream %s starts at %s after the last archived entry %s, entries in between may be missing from the archive\n", a.Stream, first, a.index.Position)
+	}
+
+	return nil
+}
+
+func (a *Archiver) write(ctx context.Context, e domain.Entry) error {
+	if a.segment == nil {
+		err := a.open(ctx, e.ID)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err := json.NewEncoder(a.segment).Encode(Record{ID: e.ID, Entry: e})
+
+	if err != nil {
+		return err
+	}
+
+	a.segment.LastID = e.ID
+	a.segment.Count++
+
+	return nil
+}
+
+func (a *Archiver) open(ctx context.Context, firstID string) error {
+	name := segmentName(a.Stream, firstID)
+	file, err := a.Backend.Create(ctx, name)
+
+	if err != nil {
+		return err
+	}
+
+	a.segment = &segment{
+		Segment: Segment{
+			Name:    name,
+			FirstID: firstID,
+			Created: a.Clock.Now(),
+		},
+		file: file,
+		gz:   gzip.NewWriter(file),
+	}
+
+	return nil
+}
+
+//discard drops the open segment so its entries are read again.
+func (a *Archiver) discard() {
+	if a.segment != nil {
+		a.segment.file.Abort()
+		a.segment = nil
+	}
+
+	a.rewind()
+}
+
+//rewind moves the position back to the last archived entry.
+func (a *Archiver) rewind() {
+	a.position = a.index.Position
+
+	if a.position == "" {
+		a.position = streamStartID
+	}
+}
+
+func (a *Archiver) pollInterval() time.Duration {
+	if a.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+
+	return a.PollInterval
+}
+
+func (a *Archiver) holdInterval() time.Duration {
+	if a.HoldInterval <= 0 {
+		return DefaultHoldInterval
+	}
+
+	return a.HoldInterval
+}
+
+func (a *Archiver) name() string {
+	if a.Name == "" {
+		return DefaultName
+	}
+
+	return a.Name
+}
+
+func (a *Archiver) maxSegmentBytes() int64 {
+	if a.MaxSegmentBytes <= 0 {
+		return DefaultMaxSegmentBytes
+	}
+
+	return a.MaxSegmentBytes
+}
+
+func (a *Archiver) maxSegmentAge() time.Duration {
+	if a.MaxSegmentAge <= 0 {
+		return DefaultMaxSegmentAge
+	}
+
+	return a.MaxSegmentAge
+}
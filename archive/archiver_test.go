@@ -0,0 +1,244 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, b Backend, name string) []Record {
+	r, err := b.Open(context.Background(), name)
+	require.Nil(t, err, "Error opening segment")
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	require.Nil(t, err, "Error reading gzip")
+
+	records := []Record{}
+	scanner := bufio.NewScanner(gz)
+
+	for scanner.Scan() {
+		var record Record
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &record), "Error decoding record")
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestArchive(t *testing.T) {
+	clock := &mock.TestClock{Time: time.Unix(1000, 0)}
+
+	newArchiver := func(t *testing.T, repo *mock.TestRepo) (*Archiver, FileBackend) {
+		dir, err := ioutil.TempDir("", "archive")
+		require.Nil(t, err, "Error creating directory")
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		backend := FileBackend{Dir: dir}
+
+		a := &Archiver{
+			Repo:            repo,
+			Backend:         backend,
+			Clock:           clock,
+			Stream:          "events",
+			MaxSegmentBytes: 1 << 20,
+			MaxSegmentAge:   time.Minute,
+		}
+
+		require.Nil(t, a.Load(context.Background()), "Error loading index")
+
+		return a, backend
+	}
+
+	t.Run("Start from the beginning of the stream", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{Next: "5-1"},
+		}
+
+		a, _ := newArchiver(t, repo)
+
+		err := a.Archive(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, domain.EntryQuery{From: "0-1", Count: readCount}, repo.QueryEntriesQuery, "Query not correct")
+		assert.Equal(t, "5-0", a.position, "Position not advanced past skipped messages")
+		assert.False(t, a.caughtUp, "Caught up before reading the whole stream")
+		assert.Nil(t, a.segment, "Segment opened without entries")
+	})
+
+	t.Run("Flush segment and resume after it", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{
+				Entries: []domain.Entry{
+					domain.Entry{ID: "1-0", ObjectID: 1, ObjectType: 2, Action: "create"},
+					domain.Entry{ID: "3-0", ObjectID: 3, ObjectType: 2, Action: "delete"},
+				},
+			},
+		}
+
+		a, backend := newArchiver(t, repo)
+
+		assert.Equal(t, "0-0", repo.HoldEntriesID, "Stream not held from the start")
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		require.Nil(t, a.Flush(context.Background()), "Error flushing")
+
+		index, err := LoadIndex(context.Background(), backend, "events")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "3-0", index.Position, "Position not correct")
+		assert.Equal(t, "events", repo.HoldEntriesStream, "Held stream not correct")
+		assert.Equal(t, DefaultName, repo.HoldEntriesName, "Hold name not correct")
+		assert.Equal(t, "3-0", repo.HoldEntriesID, "Hold not moved to the index position")
+		require.Len(t, index.Segments, 1, "Segments not correct")
+		assert.Equal(t, "events/1-0.ndjson.gz", index.Segments[0].Name, "Segment name not correct")
+		assert.Equal(t, "1-0", index.Segments[0].FirstID, "First ID not correct")
+		assert.Equal(t, "3-0", index.Segments[0].LastID, "Last ID not correct")
+		assert.Equal(t, int64(2), index.Segments[0].Count, "Count not correct")
+
+		records := readRecords(t, backend, index.Segments[0].Name)
+
+		require.Len(t, records, 2, "Records not correct")
+		assert.Equal(t, "1-0", records[0].ID, "Record ID not correct")
+		assert.Equal(t, "delete", records[1].Entry.Action, "Record entry not correct")
+
+		resumed := &Archiver{Repo: repo, Backend: backend, Clock: clock, Stream: "events"}
+		require.Nil(t, resumed.Load(context.Background()), "Error loading index")
+
+		assert.Equal(t, "3-0", resumed.position, "Archiving does not resume after the index position")
+		assert.True(t, a.caughtUp, "Not caught up at the end of the stream")
+	})
+
+	t.Run("Segments are not visible before they are flushed", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{Entries: []domain.Entry{domain.Entry{ID: "1-0"}}},
+		}
+
+		a, backend := newArchiver(t, repo)
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+
+		_, err := backend.Open(context.Background(), "events/1-0.ndjson.gz")
+		assert.Equal(t, domain.ErrNotFound, err, "Segment visible before flush")
+
+		index, err := LoadIndex(context.Background(), backend, "events")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, index.Segments, "Index updated before flush")
+	})
+
+	t.Run("Rotate segments by size", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{Entries: []domain.Entry{domain.Entry{ID: "1-0"}}},
+		}
+
+		a, backend := newArchiver(t, repo)
+		a.MaxSegmentBytes = 1
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+
+		assert.Nil(t, a.segment, "Segment not closed")
+
+		index, err := LoadIndex(context.Background(), backend, "events")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Len(t, index.Segments, 1, "Segment not added to the index")
+	})
+
+	t.Run("Rotate segments by age", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{Entries: []domain.Entry{domain.Entry{ID: "1-0"}}},
+		}
+
+		a, _ := newArchiver(t, repo)
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		require.NotNil(t, a.segment, "Segment closed too early")
+
+		a.segment.Created = clock.Time.Add(-2 * time.Minute)
+		repo.QueryEntriesReturnPage = domain.EntryPage{}
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+
+		assert.Nil(t, a.segment, "Segment not closed")
+		assert.Equal(t, "1-0", a.index.Position, "Index position not correct")
+	})
+
+	t.Run("Refresh the hold", func(t *testing.T) {
+		repo := &mock.TestRepo{}
+
+		a, _ := newArchiver(t, repo)
+		a.Clock = &mock.TestClock{Time: clock.Time}
+		repo.HoldEntriesID = ""
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		assert.Empty(t, repo.HoldEntriesID, "Hold refreshed too early")
+
+		a.Clock = &mock.TestClock{Time: clock.Time.Add(DefaultHoldInterval)}
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		assert.Equal(t, "0-0", repo.HoldEntriesID, "Hold not refreshed")
+	})
+
+	t.Run("Hold error", func(t *testing.T) {
+		repo := &mock.TestRepo{HoldEntriesReturnError: errors.New("some error")}
+
+		dir, err := ioutil.TempDir("", "archive")
+		require.Nil(t, err, "Error creating directory")
+		defer os.RemoveAll(dir)
+
+		a := &Archiver{Repo: repo, Backend: FileBackend{Dir: dir}, Clock: clock, Stream: "events"}
+
+		assert.NotNil(t, a.Load(context.Background()), "Error is nil")
+	})
+
+	t.Run("Report entries removed before they were archived", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnPage: domain.EntryPage{Entries: []domain.Entry{domain.Entry{ID: "3-0"}}},
+		}
+
+		a, backend := newArchiver(t, repo)
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		require.Nil(t, a.Flush(context.Background()), "Error flushing")
+
+		var logged bytes.Buffer
+		log.SetOutput(&logged)
+		defer log.SetOutput(os.Stderr)
+
+		resumed := &Archiver{Repo: repo, Backend: backend, Clock: clock, Stream: "events"}
+		require.Nil(t, resumed.Load(context.Background()), "Error loading index")
+
+		assert.Empty(t, logged.String(), "Gap reported while the last archived entry is in the stream")
+		assert.Equal(t, domain.EntryQuery{Count: 1}, repo.QueryEntriesQuery, "Query not correct")
+
+		repo.QueryEntriesReturnPage = domain.EntryPage{Entries: []domain.Entry{domain.Entry{ID: "7-0"}}}
+		require.Nil(t, resumed.Load(context.Background()), "Error loading index")
+
+		assert.Contains(t, logged.String(), "WARNING: stream events starts at 7-0 after the last archived entry 3-0", "Gap not reported")
+	})
+
+	t.Run("Read error keeps the position", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			QueryEntriesReturnError: errors.New("some error"),
+		}
+
+		a, _ := newArchiver(t, repo)
+
+		err := a.Archive(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Equal(t, streamStartID, a.position, "Position changed")
+	})
+}
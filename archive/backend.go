@@ -0,0 +1,117 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const tmpSuffix string = ".tmp"
+
+//Writer writes a single archive file.
+type Writer interface {
+	io.Writer
+	//Close stores the file, it is not visible before that.
+	Close() error
+	//Abort discards the file.
+	Abort() error
+}
+
+//Backend stores the archive files.
+type Backend interface {
+	//Create starts writing the named file, replacing it once the writer is closed.
+	Create(ctx context.Context, name string) (Writer, error)
+	//Open reads the named file. Returns domain.ErrNotFound if the file does not exist.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+//FileBackend stores the archive files in a directory of the local filesystem.
+type FileBackend struct {
+	Dir string
+}
+
+//Create starts writing the named file into a temporary file which is renamed when closed,
+//so readers never see partially written files.
+func (f FileBackend) Create(ctx context.Context, name string) (Writer, error) {
+	path := f.path(name)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		return nil, fmt.Errorf("Create: %s", err)
+	}
+
+	file, err := os.Create(path + tmpSuffix)
+
+	if err != nil {
+		return nil, fmt.Errorf("Create: %s", err)
+	}
+
+	return &fileWriter{File: file, path: path}, nil
+}
+
+//Open reads the named file.
+func (f FileBackend) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := os.Open(f.path(name))
+
+	if os.IsNotExist(err) {
+		return nil, domain.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Open: %s", err)
+	}
+
+	return file, nil
+}
+
+func (f FileBackend) path(name string) string {
+	return filepath.Join(f.Dir, filepath.FromSlash(name))
+}
+
+type fileWriter struct {
+	*os.File
+	path string
+}
+
+//Close syncs the temporary file to disk and moves it in place.
+func (w *fileWriter) Close() error {
+	err := w.File.Sync()
+
+	if err != nil {
+		w.Abort()
+		return fmt.Errorf("Close: %s", err)
+	}
+
+	err = w.File.Close()
+
+	if err != nil {
+		os.Remove(w.File.Name())
+		return fmt.Errorf("Close: %s", err)
+	}
+
+	err = os.Rename(w.File.Name(), w.path)
+
+	if err != nil {
+		return fmt.Errorf("Close: %s", err)
+	}
+
+	return nil
+}
+
+//Abort removes the temporary file.
+func (w *fileWriter) Abort() error {
+	w.File.Close()
+
+	err := os.Remove(w.File.Name())
+
+	if err != nil {
+		return fmt.Errorf("Abort: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testObjectStore struct {
+	objects map[string][]byte
+}
+
+func (s *testObjectStore) PutObject(ctx context.Context, key string, body io.Reader, size int64) error {
+	content, err := ioutil.ReadAll(body)
+	s.objects[key] = content
+	return err
+}
+
+func (s *testObjectStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, ok := s.objects[key]
+
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func testBackend(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	w, err := b.Create(ctx, "events/file")
+	require.Nil(t, err, "Error creating file")
+
+	_, err = w.Write([]byte("content"))
+	require.Nil(t, err, "Error writing file")
+
+	_, err = b.Open(ctx, "events/file")
+	assert.Equal(t, domain.ErrNotFound, err, "File visible before it was closed")
+
+	require.Nil(t, w.Close(), "Error closing file")
+
+	r, err := b.Open(ctx, "events/file")
+	require.Nil(t, err, "Error opening file")
+
+	content, err := ioutil.ReadAll(r)
+	r.Close()
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Equal(t, "content", string(content), "Content not correct")
+
+	w, err = b.Create(ctx, "events/aborted")
+	require.Nil(t, err, "Error creating file")
+
+	_, err = w.Write([]byte("content"))
+	require.Nil(t, err, "Error writing file")
+	require.Nil(t, w.Abort(), "Error aborting file")
+
+	_, err = b.Open(ctx, "events/aborted")
+	assert.Equal(t, domain.ErrNotFound, err, "Aborted file visible")
+}
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(t, err, "Error creating directory")
+	defer os.RemoveAll(dir)
+
+	testBackend(t, FileBackend{Dir: dir})
+}
+
+func TestObjectBackend(t *testing.T) {
+	store := &testObjectStore{objects: map[string][]byte{}}
+
+	testBackend(t, ObjectBackend{Store: store, Prefix: "archive"})
+
+	assert.Contains(t, store.objects, "archive/events/file", "Object key not correct")
+}
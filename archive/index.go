@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	indexFile     string = "index.json"
+	segmentSuffix string = ".ndjson.gz"
+)
+
+//Segment describes a single archive file and the range of entries in it.
+type Segment struct {
+	Name    string    `json:"name"`
+	FirstID string    `json:"first_id"`
+	LastID  string    `json:"last_id"`
+	Count   int64     `json:"count"`
+	Created time.Time `json:"created"`
+}
+
+//Index lists the segments of an archived stream in the order of their IDs.
+//Position is the ID of the last message archived, archiving resumes after it.
+type Index struct {
+	Stream   string    `json:"stream"`
+	Position string    `json:"position"`
+	Segments []Segment `json:"segments"`
+}
+
+//Record is a single line of a segment.
+type Record struct {
+	ID    string       `json:"id"`
+	Entry domain.Entry `json:"entry"`
+}
+
+//LoadIndex reads the index of the stream archive.
+//Returns an empty index if nothing was archived yet.
+func LoadIndex(ctx context.Context, b Backend, stream string) (Index, error) {
+	index := Index{Stream: stream, Segments: []Segment{}}
+
+	r, err := b.Open(ctx, indexName(stream))
+
+	if err == domain.ErrNotFound {
+		return index, nil
+	}
+
+	if err != nil {
+		return index, fmt.Errorf("LoadIndex: %s", err)
+	}
+
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(&index)
+
+	if err != nil {
+		return index, fmt.Errorf("LoadIndex: %s", err)
+	}
+
+	return index, nil
+}
+
+//Find returns the segments which may hold entries with IDs greater than or equal to the given one.
+func (i Index) Find(ID string) ([]Segment, error) {
+	for n, s := range i.Segments {
+		cmp, err := domain.CompareIDs(s.LastID, ID)
+
+		if err != nil {
+			return nil, fmt.Errorf("Find: %s", err)
+		}
+
+		if cmp >= 0 {
+			return i.Segments[n:], nil
+		}
+	}
+
+	return []Segment{}, nil
+}
+
+//store replaces the stored index with this one.
+func (i Index) store(ctx context.Context, b Backend) error {
+	w, err := b.Create(ctx, indexName(i.Stream))
+
+	if err != nil {
+		return fmt.Errorf("store: %s", err)
+	}
+
+	err = json.NewEncoder(w).Encode(i)
+
+	if err != nil {
+		w.Abort()
+		return fmt.Errorf("store: %s", err)
+	}
+
+	return w.Close()
+}
+
+func indexName(stream string) string {
+	return path.Join(stream, indexFile)
+}
+
+func segmentName(stream, firstID string) string {
+	return path.Join(stream, firstID+segmentSuffix)
+}
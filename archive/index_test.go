@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	index := Index{
+		Stream: "events",
+		Segments: []Segment{
+			Segment{Name: "events/1-0.ndjson.gz", FirstID: "1-0", LastID: "10-0"},
+			Segment{Name: "events/11-0.ndjson.gz", FirstID: "11-0", LastID: "20-3"},
+			Segment{Name: "events/21-0.ndjson.gz", FirstID: "21-0", LastID: "30-0"},
+		},
+	}
+
+	t.Run("From the start", func(t *testing.T) {
+		segments, err := index.Find("0-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, index.Segments, segments, "Segments not correct")
+	})
+
+	t.Run("From the middle of a segment", func(t *testing.T) {
+		segments, err := index.Find("20-1")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, index.Segments[1:], segments, "Segments not correct")
+	})
+
+	t.Run("After the last segment", func(t *testing.T) {
+		segments, err := index.Find("31-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, segments, "Segments not correct")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		_, err := index.Find("abc")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+)
+
+//ObjectStore is the part of an S3-compatible client the archive needs.
+//No client is bundled, wrap the one of your provider to use it.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body io.Reader, size int64) error
+	//GetObject returns domain.ErrNotFound if the object does not exist.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+//ObjectBackend stores the archive files as objects in an S3-compatible object store.
+//Files are kept in memory while written and uploaded in one piece when closed.
+type ObjectBackend struct {
+	Store ObjectStore
+
+	//Prefix is put in front of the file names to get the object keys
+	Prefix string
+}
+
+//Create starts writing the named file, the object is uploaded when the writer is closed.
+func (o ObjectBackend) Create(ctx context.Context, name string) (Writer, error) {
+	return &objectWriter{ctx: ctx, store: o.Store, key: o.key(name)}, nil
+}
+
+//Open reads the named file.
+func (o ObjectBackend) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return o.Store.GetObject(ctx, o.key(name))
+}
+
+func (o ObjectBackend) key(name string) string {
+	if o.Prefix == "" {
+		return name
+	}
+
+	return path.Join(o.Prefix, name)
+}
+
+type objectWriter struct {
+	bytes.Buffer
+	ctx   context.Context
+	store ObjectStore
+	key   string
+}
+
+//Close uploads the buffered file.
+func (w *objectWriter) Close() error {
+	err := w.store.PutObject(w.ctx, w.key, &w.Buffer, int64(w.Len()))
+
+	if err != nil {
+		return fmt.Errorf("Close: %s", err)
+	}
+
+	return nil
+}
+
+//Abort drops the buffered file.
+func (w *objectWriter) Abort() error {
+	w.Reset()
+	return nil
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/streamer"
+)
+
+const (
+	//replayBatch is the number of archived entries returned at once
+	replayBatch int = 100
+)
+
+//Replayer streams the archived entries of the streams before handing over to the live streamer.
+//The live streamer starts after the last archived entry replayed on each stream.
+//Entries of the archive are not tracked by a cursor, a replay that stops is started over.
+type Replayer struct {
+	Backend Backend
+	Live    *streamer.RedisStreamer
+
+	//Start maps the names of the streams to the positions to replay them after, see streamer.StartFrom.
+	Start map[string]string
+
+	positions map[string]string
+	pending   []string
+	stream    string
+	segments  []Segment
+	reader    *SegmentReader
+	archived  map[string]bool
+	live      bool
+}
+
+//GetEntries returns the next archived entries, and the live ones once the archive is replayed.
+func (r *Replayer) GetEntries(ctx context.Context) ([]domain.Entry, error) {
+	if r.live {
+		return r.Live.GetEntries(ctx)
+	}
+
+	if r.positions == nil {
+		r.positions = make(map[string]string, len(r.Start))
+		r.archived = make(map[string]bool)
+
+		for stream, position := range r.Start {
+			r.positions[stream] = position
+			r.pending = append(r.pending, stream)
+		}
+
+		sort.Strings(r.pending)
+	}
+
+	entries, err := r.next(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetEntries: %s", err)
+	}
+
+	if len(entries) > 0 {
+		return entries, nil
+	}
+
+	log.Println("Archive replayed, switching to live entries")
+
+	r.Live.Start = r.positions
+	r.archived = nil
+	r.live = true
+
+	return r.Live.GetEntries(ctx)
+}
+
+//MarkEntryProcessed marks archived entries in memory and live ones with the live streamer.
+func (r *Replayer) MarkEntryProcessed(ctx context.Context, ID string) error {
+	if r.archived[ID] {
+		delete(r.archived, ID)
+		return nil
+	}
+
+	return r.Live.MarkEntryProcessed(ctx, ID)
+}
+
+//CountAttempt counts the attempts of live entries with the live streamer, archived ones are not tracked.
+func (r *Replayer) CountAttempt(ctx context.Context, ID string) (int, error) {
+	if r.archived[ID] {
+		return 0, nil
+	}
+
+	return r.Live.CountAttempt(ctx, ID)
+}
+
+//Release releases the live streamer.
+func (r *Replayer) Release(ctx context.Context) error {
+	if r.reader != nil {
+		r.reader.Close()
+		r.reader = nil
+	}
+
+	return r.Live.Release(ctx)
+}
+
+//Beat keeps the heart of the live streamer alive.
+func (r *Replayer) Beat(ctx context.Context) error {
+	return r.Live.Beat(ctx)
+}
+
+//next reads the next batch of archived entries after the positions.
+//Returns no entries once all the streams are replayed.
+func (r *Replayer) next(ctx context.Context) ([]domain.Entry, error) {
+	entries := make([]domain.Entry, 0, replayBatch)
+
+	for len(entries) < replayBatch {
+		if r.reader == nil {
+			ok, err := r.openNext(ctx)
+
+			if err != nil || !ok {
+				return entries, err
+			}
+		}
+
+		record, err := r.reader.Next()
+
+		if err == io.EOF {
+			r.reader.Close()
+			r.reader = nil
+			continue
+		}
+
+		if err != nil {
+			return entries, err
+		}
+
+		cmp, err := domain.CompareIDs(record.ID, r.positions[r.stream])
+
+		if err != nil {
+			return entries, err
+		}
+
+		//Segments can start before the position.
+		if cmp <= 0 {
+			continue
+		}
+
+		e := record.Entry
+		e.ID, e.Stream = record.ID, r.stream
+
+		r.positions[r.stream] = e.ID
+		r.archived[e.ID] = true
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+//openNext opens the next segment to replay, moving on to the next stream when needed.
+//Returns false once there are no more segments.
+func (r *Replayer) openNext(ctx context.Context) (bool, error) {
+	for len(r.segments) == 0 {
+		if len(r.pending) == 0 {
+			return false, nil
+		}
+
+		r.stream, r.pending = r.pending[0], r.pending[1:]
+
+		index, err := LoadIndex(ctx, r.Backend, r.stream)
+
+		if err != nil {
+			return false, err
+		}
+
+		r.segments, err = index.Find(r.positions[r.stream])
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	reader, err := OpenSegment(ctx, r.Backend, r.segments[0].Name)
+
+	if err != nil {
+		return false, err
+	}
+
+	r.reader, r.segments = reader, r.segments[1:]
+
+	return true, nil
+}
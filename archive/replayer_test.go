@@ -0,0 +1,106 @@
+package archive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/antekresic/grs/streamer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//archiveEntries writes every batch of entries into its own segment.
+func archiveEntries(t *testing.T, backend Backend, stream string, batches ...[]domain.Entry) {
+	repo := &mock.TestRepo{}
+
+	a := &Archiver{
+		Repo:    repo,
+		Backend: backend,
+		Clock:   mock.TestClock{Time: time.Unix(1000, 0)},
+		Stream:  stream,
+	}
+
+	require.Nil(t, a.Load(context.Background()), "Error loading index")
+
+	for _, entries := range batches {
+		repo.QueryEntriesReturnPage = domain.EntryPage{Entries: entries}
+
+		require.Nil(t, a.Archive(context.Background()), "Error archiving")
+		require.Nil(t, a.Flush(context.Background()), "Error flushing")
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(t, err, "Error creating directory")
+	defer os.RemoveAll(dir)
+
+	backend := FileBackend{Dir: dir}
+
+	archiveEntries(t, backend, "events",
+		[]domain.Entry{domain.Entry{ID: "1-0", ObjectID: 1}, domain.Entry{ID: "2-0", ObjectID: 2}},
+		[]domain.Entry{domain.Entry{ID: "3-0", ObjectID: 3}},
+	)
+
+	t.Run("Replay the archive after the start position, then switch to live", func(t *testing.T) {
+		liveRepo := &mock.TestRepo{
+			GetEntriesReturnEntries: []domain.Entry{domain.Entry{ID: "4-0", Stream: "events"}},
+		}
+
+		r := &Replayer{
+			Backend: backend,
+			Live:    &streamer.RedisStreamer{Repo: liveRepo, Clock: mock.TestClock{Time: time.Now()}},
+			Start:   map[string]string{"events": "1-0", "other": "0-0"},
+		}
+
+		entries, err := r.GetEntries(context.Background())
+
+		require.Nil(t, err, "Error is not nil")
+		require.Len(t, entries, 2, "Archived entries not correct")
+		assert.Equal(t, "2-0", entries[0].ID, "Entry ID not correct")
+		assert.Equal(t, 2, entries[0].ObjectID, "Entry not correct")
+		assert.Equal(t, "events", entries[0].Stream, "Entry stream not correct")
+		assert.Equal(t, "3-0", entries[1].ID, "Entry ID not correct")
+
+		for _, e := range entries {
+			assert.Nil(t, r.MarkEntryProcessed(context.Background(), e.ID), "Error marking archived entry")
+		}
+
+		assert.Nil(t, liveRepo.StoreCursorCursor.Positions, "Archived entries marked on the live cursor")
+
+		entries, err = r.GetEntries(context.Background())
+
+		require.Nil(t, err, "Error is not nil")
+		assert.Equal(t, liveRepo.GetEntriesReturnEntries, entries, "Live entries not correct")
+		assert.Equal(
+			t,
+			map[string]string{"events": "3-0", "other": "0-0"},
+			liveRepo.GetEntriesPositions,
+			"Live streamer does not continue after the archive",
+		)
+
+		assert.Nil(t, r.MarkEntryProcessed(context.Background(), "4-0"), "Error marking live entry")
+		assert.Equal(t, map[string]string{"events": "4-0"}, liveRepo.StoreCursorCursor.Positions, "Live entry not marked")
+	})
+
+	t.Run("Start after the archive", func(t *testing.T) {
+		liveRepo := &mock.TestRepo{}
+
+		r := &Replayer{
+			Backend: backend,
+			Live:    &streamer.RedisStreamer{Repo: liveRepo, Clock: mock.TestClock{Time: time.Now()}},
+			Start:   map[string]string{"events": "3-0"},
+		}
+
+		entries, err := r.GetEntries(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, entries, "Entries not correct")
+		assert.Equal(t, map[string]string{"events": "3-0"}, liveRepo.GetEntriesPositions, "Live start not correct")
+	})
+}
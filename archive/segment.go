@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+//SegmentReader reads the records of a segment one by one.
+type SegmentReader struct {
+	file    io.ReadCloser
+	gz      *gzip.Reader
+	decoder *json.Decoder
+}
+
+//OpenSegment starts reading the named segment.
+func OpenSegment(ctx context.Context, b Backend, name string) (*SegmentReader, error) {
+	file, err := b.Open(ctx, name)
+
+	if err != nil {
+		return nil, fmt.Errorf("OpenSegment: %s", err)
+	}
+
+	gz, err := gzip.NewReader(file)
+
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("OpenSegment: %s", err)
+	}
+
+	return &SegmentReader{file: file, gz: gz, decoder: json.NewDecoder(gz)}, nil
+}
+
+//Next returns the next record of the segment or io.EOF at the end of it.
+func (s *SegmentReader) Next() (Record, error) {
+	var record Record
+
+	err := s.decoder.Decode(&record)
+
+	if err == io.EOF {
+		return record, err
+	}
+
+	if err != nil {
+		return record, fmt.Errorf("Next: %s", err)
+	}
+
+	return record, nil
+}
+
+//Close stops reading the segment.
+func (s *SegmentReader) Close() error {
+	s.gz.Close()
+	return s.file.Close()
+}
@@ -0,0 +1,277 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const metaPrefix string = "meta."
+
+//entryFields are the fields of an entry rules can refer to besides the keys of the meta
+var entryFields = map[string]bool{
+	"object_id":       true,
+	"object_type":     true,
+	"action":          true,
+	"schema_version":  true,
+	"idempotency_key": true,
+}
+
+//Rules is an entry validator configured with rules loaded from a JSON file.
+//Fields are named like in the JSON of the entry, keys of the meta object are prefixed with meta,
+//so meta.name is the name key and meta.address.city is the city key of the address object.
+type Rules struct {
+	//ObjectTypes maps the allowed object types to their rules, any object type is allowed when empty
+	ObjectTypes map[int]TypeRules `json:"object_types"`
+
+	//Required are the fields every entry must have
+	Required []string `json:"required"`
+	//Fields are the limits on the values of the fields of every entry
+	Fields map[string]FieldRule `json:"fields"`
+}
+
+//TypeRules are the rules for the entries of a single object type.
+type TypeRules struct {
+	//Actions are the allowed actions, any action is allowed when empty
+	Actions []string `json:"actions"`
+
+	//Required are the fields the entries must have
+	Required []string `json:"required"`
+	//Fields are the limits on the values of the fields
+	Fields map[string]FieldRule `json:"fields"`
+}
+
+//FieldRule limits the value of a field.
+//Min and Max limit numbers, Pattern is a regular expression strings must match.
+type FieldRule struct {
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	Pattern string   `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+//LoadRules reads the rules from a JSON file.
+//Unknown settings, unknown fields and invalid patterns are errors so mistakes never disable a rule.
+func LoadRules(path string) (Rules, error) {
+	var r Rules
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return r, fmt.Errorf("LoadRules: %s", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&r)
+
+	if err != nil {
+		return r, fmt.Errorf("LoadRules: %s", err)
+	}
+
+	err = compileFields(r.Required, r.Fields)
+
+	if err != nil {
+		return r, fmt.Errorf("LoadRules: %s", err)
+	}
+
+	for objectType, t := range r.ObjectTypes {
+		err = compileFields(t.Required, t.Fields)
+
+		if err != nil {
+			return r, fmt.Errorf("LoadRules: object type %d: %s", objectType, err)
+		}
+	}
+
+	return r, nil
+}
+
+//compileFields checks the field names and compiles the patterns of the rules.
+func compileFields(required []string, fields map[string]FieldRule) error {
+	for _, name := range required {
+		if !validField(name) {
+			return fmt.Errorf("unknown field %s", name)
+		}
+	}
+
+	for name, rule := range fields {
+		if !validField(name) {
+			return fmt.Errorf("unknown field %s", name)
+		}
+
+		if rule.Pattern == "" {
+			continue
+		}
+
+		var err error
+		rule.pattern, err = regexp.Compile(rule.Pattern)
+
+		if err != nil {
+			return fmt.Errorf("field %s: %s", name, err)
+		}
+
+		fields[name] = rule
+	}
+
+	return nil
+}
+
+func validField(name string) bool {
+	return entryFields[name] || (strings.HasPrefix(name, metaPrefix) && len(name) > len(metaPrefix))
+}
+
+//Validate is used to validate entries against the rules.
+//Every broken rule is returned in a domain.ValidationError.
+func (r Rules) Validate(ctx context.Context, e domain.Entry) error {
+	values := entryValues(e)
+	errs := checkFields(values, r.Required, r.Fields)
+
+	t, ok := r.ObjectTypes[e.ObjectType]
+
+	if len(r.ObjectTypes) > 0 && !ok {
+		errs = append(errs, domain.FieldError{Path: "/object_type", Message: "is not allowed"})
+	}
+
+	if ok {
+		if len(t.Actions) > 0 && !contains(t.Actions, e.Action) {
+			errs = append(errs, domain.FieldError{
+				Path:    "/action",
+				Message: fmt.Sprintf("must be one of %s for object type %d", strings.Join(t.Actions, ", "), e.ObjectType),
+			})
+		}
+
+		errs = append(errs, checkFields(values, t.Required, t.Fields)...)
+	}
+
+	if len(errs) > 0 {
+		return &domain.ValidationError{Errors: errs}
+	}
+
+	return nil
+}
+
+//checkFields checks the values against the required fields and the field rules, sorted by field name.
+func checkFields(values func(name string) (interface{}, bool), required []string, fields map[string]FieldRule) []domain.FieldError {
+	var errs []domain.FieldError
+
+	for _, name := range required {
+		if _, ok := values(name); !ok {
+			errs = append(errs, domain.FieldError{Path: fieldPath(name), Message: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		//Missing fields are only checked by required.
+		value, ok := values(name)
+
+		if !ok {
+			continue
+		}
+
+		for _, message := range fields[name].check(value) {
+			errs = append(errs, domain.FieldError{Path: fieldPath(name), Message: message})
+		}
+	}
+
+	return errs
+}
+
+func (f FieldRule) check(value interface{}) []string {
+	var messages []string
+
+	if f.Min != nil || f.Max != nil {
+		n, ok := value.(float64)
+
+		switch {
+		case !ok:
+			messages = append(messages, "must be a number")
+		case f.Min != nil && n < *f.Min:
+			messages = append(messages, fmt.Sprintf("must be at least %g", *f.Min))
+		case f.Max != nil && n > *f.Max:
+			messages = append(messages, fmt.Sprintf("must be at most %g", *f.Max))
+		}
+	}
+
+	if f.pattern != nil {
+		s, ok := value.(string)
+
+		switch {
+		case !ok:
+			messages = append(messages, "must be a string")
+		case !f.pattern.MatchString(s):
+			messages = append(messages, "must match the pattern "+f.Pattern)
+		}
+	}
+
+	return messages
+}
+
+//entryValues returns a lookup of the values of the entry fields, numbers are float64 like in decoded JSON.
+//Fields with zero values and meta keys which are missing or null are reported as missing.
+func entryValues(e domain.Entry) func(name string) (interface{}, bool) {
+	var meta interface{}
+
+	//Meta which is not valid JSON has no keys.
+	json.Unmarshal(e.Meta, &meta)
+
+	return func(name string) (interface{}, bool) {
+		switch name {
+		case "object_id":
+			return float64(e.ObjectID), e.ObjectID != 0
+		case "object_type":
+			return float64(e.ObjectType), e.ObjectType != 0
+		case "action":
+			return e.Action, e.Action != ""
+		case "schema_version":
+			return float64(e.SchemaVersion), e.SchemaVersion != 0
+		case "idempotency_key":
+			return e.IdempotencyKey, e.IdempotencyKey != ""
+		}
+
+		value := meta
+
+		for _, key := range strings.Split(strings.TrimPrefix(name, metaPrefix), ".") {
+			object, ok := value.(map[string]interface{})
+
+			if !ok {
+				return nil, false
+			}
+
+			value = object[key]
+		}
+
+		return value, value != nil
+	}
+}
+
+//fieldPath returns the JSON pointer to the field.
+func fieldPath(name string) string {
+	return "/" + strings.Replace(name, ".", "/", -1)
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `{
+	"required": ["meta.source"],
+	"fields": {
+		"object_id": {"min": 1, "max": 1000}
+	},
+	"object_types": {
+		"2": {
+			"actions": ["create", "update"],
+			"required": ["meta.name"],
+			"fields": {
+				"meta.name": {"pattern": "^[a-z ]+$"},
+				"meta.address.zip": {"min": 10000, "max": 99999}
+			}
+		},
+		"3": {}
+	}
+}`
+
+func loadTestRules(t *testing.T, content string) (Rules, error) {
+	dir, err := ioutil.TempDir("", "rules")
+	require.Nil(t, err, "Error creating directory")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "rules.json")
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644), "Error writing rules")
+
+	return LoadRules(path)
+}
+
+func TestRules(t *testing.T) {
+	r, err := loadTestRules(t, testRules)
+	require.Nil(t, err, "Error loading rules")
+
+	entry := func(objectID, objectType int, action, meta string) domain.Entry {
+		return domain.Entry{ObjectID: objectID, ObjectType: objectType, Action: action, Meta: json.RawMessage(meta)}
+	}
+
+	t.Run("Valid entry", func(t *testing.T) {
+		err := r.Validate(context.Background(), entry(1, 2, "create", `{"source":"crm","name":"my object","address":{"zip":10000}}`))
+
+		assert.Nil(t, err, "Error is not nil")
+	})
+
+	t.Run("Object type without rules", func(t *testing.T) {
+		err := r.Validate(context.Background(), entry(1, 3, "delete", `{"source":"crm"}`))
+
+		assert.Nil(t, err, "Error is not nil")
+	})
+
+	t.Run("Object type not allowed", func(t *testing.T) {
+		err := r.Validate(context.Background(), entry(1, 4, "create", `{"source":"crm"}`))
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{{Path: "/object_type", Message: "is not allowed"}}},
+			err,
+			"Error not correct",
+		)
+	})
+
+	t.Run("Every broken rule is reported", func(t *testing.T) {
+		err := r.Validate(context.Background(), entry(1001, 2, "delete", `{"name":"My Object","address":{"zip":"10000"}}`))
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{
+				{Path: "/meta/source", Message: "is required"},
+				{Path: "/object_id", Message: "must be at most 1000"},
+				{Path: "/action", Message: "must be one of create, update for object type 2"},
+				{Path: "/meta/address/zip", Message: "must be a number"},
+				{Path: "/meta/name", Message: "must match the pattern ^[a-z ]+$"},
+			}},
+			err,
+			"Error not correct",
+		)
+	})
+
+	t.Run("Meta which is not an object", func(t *testing.T) {
+		err := r.Validate(context.Background(), entry(1, 3, "create", `"JSON"`))
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{{Path: "/meta/source", Message: "is required"}}},
+			err,
+			"Error not correct",
+		)
+	})
+}
+
+func TestLoadRules(t *testing.T) {
+	t.Run("Unknown setting", func(t *testing.T) {
+		_, err := loadTestRules(t, `{"object_typez": {}}`)
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := loadTestRules(t, `{"object_types": {"2": {"fields": {"objectid": {"min": 1}}}}}`)
+
+		assert.EqualError(t, err, "LoadRules: object type 2: unknown field objectid", "Error not correct")
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := loadTestRules(t, `{"fields": {"meta.name": {"pattern": "("}}}`)
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
@@ -1,6 +1,11 @@
 package check
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
 	"github.com/antekresic/grs/domain"
 	"github.com/go-playground/validator"
 )
@@ -10,7 +15,88 @@ type Entry struct {
 	Validator *validator.Validate
 }
 
-//Validate is used to validate entries
-func (e Entry) Validate(entry domain.Entry) error {
-	return e.Validator.Struct(entry)
+//Validate is used to validate entries.
+//Broken tags are returned as a domain.ValidationError.
+func (e Entry) Validate(ctx context.Context, entry domain.Entry) error {
+	err := e.Validator.StructCtx(ctx, entry)
+
+	fieldErrors, ok := err.(validator.ValidationErrors)
+
+	if !ok {
+		return err
+	}
+
+	invalid := &domain.ValidationError{Errors: make([]domain.FieldError, len(fieldErrors))}
+
+	for i, f := range fieldErrors {
+		invalid.Errors[i] = domain.FieldError{Path: "/" + jsonName(f.StructField()), Message: tagMessage(f)}
+	}
+
+	return invalid
+}
+
+//jsonName returns the name of the entry field in JSON.
+func jsonName(field string) string {
+	f, ok := reflect.TypeOf(domain.Entry{}).FieldByName(field)
+
+	if !ok {
+		return field
+	}
+
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+	if name == "" || name == "-" {
+		return field
+	}
+
+	return name
+}
+
+//tagMessage describes the validation tag the field broke.
+func tagMessage(f validator.FieldError) string {
+	switch f.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(f.Param()), ", ")
+	case "min":
+		return "must be at least " + f.Param()
+	default:
+		return fmt.Sprintf("breaks the %s rule", f.Tag())
+	}
+}
+
+//All runs the validators in order and merges the field errors of all of them.
+//Any other error is returned right away.
+type All []domain.EntryValidator
+
+//Validate is used to validate entries with all the validators
+func (a All) Validate(ctx context.Context, entry domain.Entry) error {
+	var merged *domain.ValidationError
+
+	for _, v := range a {
+		err := v.Validate(ctx, entry)
+
+		if err == nil {
+			continue
+		}
+
+		invalid, ok := domain.AsValidationError(err)
+
+		if !ok {
+			return err
+		}
+
+		if merged == nil {
+			merged = &domain.ValidationError{}
+		}
+
+		merged.Errors = append(merged.Errors, invalid.Errors...)
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	return merged
 }
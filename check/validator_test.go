@@ -0,0 +1,78 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntry(t *testing.T) {
+	v := Entry{Validator: validator.New()}
+
+	t.Run("Valid entry", func(t *testing.T) {
+		err := v.Validate(context.Background(), domain.Entry{ObjectID: 1, ObjectType: 2, Action: "create"})
+
+		assert.Nil(t, err, "Error is not nil")
+	})
+
+	t.Run("Broken tags", func(t *testing.T) {
+		err := v.Validate(context.Background(), domain.Entry{ObjectType: 2, Action: "remove", SchemaVersion: -1})
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{
+				{Path: "/object_id", Message: "is required"},
+				{Path: "/action", Message: "must be one of create, update, delete"},
+				{Path: "/schema_version", Message: "must be at least 0"},
+			}},
+			err,
+			"Error not correct",
+		)
+	})
+}
+
+type testValidator struct {
+	err error
+}
+
+func (v testValidator) Validate(ctx context.Context, e domain.Entry) error {
+	return v.err
+}
+
+func TestAll(t *testing.T) {
+	first := &domain.ValidationError{Errors: []domain.FieldError{{Path: "/object_id", Message: "is required"}}}
+	second := &domain.ValidationError{Errors: []domain.FieldError{{Path: "/meta/name", Message: "is required"}}}
+
+	t.Run("Valid entry", func(t *testing.T) {
+		err := All{testValidator{}, testValidator{}}.Validate(context.Background(), domain.Entry{})
+
+		assert.Nil(t, err, "Error is not nil")
+	})
+
+	t.Run("Merge field errors", func(t *testing.T) {
+		err := All{testValidator{first}, testValidator{}, testValidator{second}}.Validate(context.Background(), domain.Entry{})
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{
+				{Path: "/object_id", Message: "is required"},
+				{Path: "/meta/name", Message: "is required"},
+			}},
+			err,
+			"Error not correct",
+		)
+		assert.Len(t, first.Errors, 1, "Validator error modified")
+	})
+
+	t.Run("Stop on other errors", func(t *testing.T) {
+		failed := errors.New("some error")
+
+		err := All{testValidator{first}, testValidator{failed}, testValidator{second}}.Validate(context.Background(), domain.Entry{})
+
+		assert.Equal(t, failed, err, "Error not correct")
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/antekresic/grs/archive"
+	"github.com/antekresic/grs/storage"
+	"github.com/antekresic/grs/streamer"
+	"github.com/go-redis/redis"
+)
+
+var (
+	redisAddr = flag.String("redis-address", ":6379", "Redis address")
+
+	stream    = flag.String("stream", storage.DefaultStream, "Name of the archived stream")
+	namespace = flag.String("namespace", "", "Prefix of all the Redis keys, used to run independent pipelines on one Redis")
+	name      = flag.String("name", archive.DefaultName, "Name of the retention hold, archivers of the same stream need different names")
+
+	dir         = flag.String("dir", "archive", "Directory the segments and the index are written to")
+	segmentSize = flag.Int64("segment-size", archive.DefaultMaxSegmentBytes, "Uncompressed size in bytes at which a segment is closed")
+	segmentAge  = flag.Duration("segment-age", archive.DefaultMaxSegmentAge, "Time after which a segment is closed")
+	poll        = flag.Duration("poll", archive.DefaultPollInterval, "Time between two reads once the archiver caught up with the stream")
+	holdRefresh = flag.Duration("hold-refresh", archive.DefaultHoldInterval, "Time between two refreshes of the retention hold")
+)
+
+func main() {
+	flag.Parse()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: *redisAddr,
+	})
+
+	_, err := redisClient.Ping().Result()
+
+	if err != nil {
+		log.Fatal("Redis connection error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		log.Printf("Received %s, shutting down", <-signals)
+		cancel()
+	}()
+
+	repo := &storage.RedisRepository{
+		Client: redisClient,
+		Config: storage.Config{
+			Stream:    *stream,
+			Namespace: *namespace,
+		},
+	}
+
+	a := archive.Archiver{
+		Repo:            repo,
+		Backend:         archive.FileBackend{Dir: *dir},
+		Clock:           streamer.RealClock{},
+		Stream:          *stream,
+		Name:            *name,
+		MaxSegmentBytes: *segmentSize,
+		MaxSegmentAge:   *segmentAge,
+		PollInterval:    *poll,
+		HoldInterval:    *holdRefresh,
+	}
+
+	err = a.Run(ctx)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Archiver stopped")
+}
@@ -1,22 +1,71 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/antekresic/grs/archive"
 	"github.com/antekresic/grs/consumer"
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
 	"github.com/antekresic/grs/storage"
 	"github.com/antekresic/grs/streamer"
 	"github.com/go-redis/redis"
+	uuid "github.com/satori/go.uuid"
 )
 
 var (
 	redisAddr = flag.String("redis-address", ":6379", "Redis address")
+	mode      = flag.String("mode", "cursor", "Consuming mode: cursor or group")
+	group     = flag.String("group", streamer.DefaultGroup, "Consumer group name used in group mode")
+
+	stream           = flag.String("stream", storage.DefaultStream, "Name of the entries stream")
+	deadLetterStream = flag.String("dlq-stream", storage.DefaultDeadLetterStream, "Name of the dead letter queue stream")
+	namespace        = flag.String("namespace", "", "Prefix of all the Redis keys, used to run independent pipelines on one Redis")
+	streams          = flag.String("streams", "", "Comma separated streams read in cursor mode, the entries stream if empty")
+
+	heartTimeout  = flag.Duration("heart-timeout", streamer.DefaultHeartTimeout, "Time without a heartbeat after which the consumer is considered dead")
+	heartInterval = flag.Duration("heart-interval", streamer.DefaultHeartInterval, "Time between two heartbeats, must be lower than the heart timeout")
+
+	reclaimInterval = flag.Duration("reclaim-interval", streamer.DefaultHeartTimeout, "Time between reclaiming entries of dead consumers in group mode")
+	reclaimIdle     = flag.Duration("reclaim-idle", streamer.DefaultHeartTimeout, "Pending time after which entries of live consumers are reclaimed in group mode")
+	reclaimMax      = flag.Int64("reclaim-max", streamer.DefaultMaxClaims, "Maximum number of entries reclaimed in one pass in group mode")
+
+	retention         = flag.Duration("retention", 0, "Age after which entries are trimmed from the read streams, entries are kept forever when 0")
+	retentionInterval = flag.Duration("retention-interval", streamer.DefaultTrimInterval, "Time between two trim passes")
+	retentionSafe     = flag.Bool("retention-safe", true, "Keep the entries not yet processed by live consumers, however old they are")
+
+	startFrom     = flag.String("start-from", "", "Entry ID a new cursor starts reading at instead of new entries, 0 replays the whole stream")
+	startTime     = flag.String("start-time", "", "RFC3339 time a new cursor starts reading at instead of new entries")
+	replayArchive = flag.String("replay-archive", "", "Archiver directory the entries older than the stream are replayed from first")
+
+	metricsAddr = flag.String("metrics-address", "", "Address metrics are served on for scraping at /metrics, like :9100, disabled when empty")
+
+	maxRetries = flag.Int("max-retries", consumer.DefaultMaxRetries, "Number of retries before an entry is dead-lettered")
+	backoff    = flag.Duration("retry-backoff", consumer.DefaultBackoff, "Wait before the first retry, doubled with each next one")
+
+	shutdownTimeout = flag.Duration("shutdown-timeout", consumer.DefaultShutdownTimeout, "Time the entry being processed may take to finish after SIGINT or SIGTERM")
+
+	handler = flag.String("handler", "printer", "Handler consuming all the entries, ignored if a config file is given")
+	config  = flag.String("config", "", "JSON file routing entries to handlers by object type and action")
 )
 
 func main() {
 	flag.Parse()
 
+	if *heartInterval >= *heartTimeout {
+		log.Fatal("Heart interval must be lower than the heart timeout")
+	}
+
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: *redisAddr,
 	})
@@ -27,16 +76,239 @@ func main() {
 		log.Fatal("Redis connection error:", err)
 	}
 
-	s := &streamer.RedisStreamer{
-		Repo: &storage.RedisRepository{
-			Client: redisClient,
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		log.Printf("Received %s, shutting down", <-signals)
+		cancel()
+	}()
+
+	repo := &storage.RedisRepository{
+		Client: redisClient,
+		Config: storage.Config{
+			Stream:           *stream,
+			DeadLetterStream: *deadLetterStream,
+			Namespace:        *namespace,
+			Subscriptions:    splitList(*streams),
 		},
-		Clock: streamer.RealClock{},
 	}
 
-	c := consumer.Printer{
+	var registry *metrics.Registry
+
+	if *metricsAddr != "" {
+		registry = metrics.NewRegistry()
+
+		m := streamer.Monitor{
+			Repo:    repo,
+			Streams: readStreams(),
+		}
+
+		m.Register(registry)
+
+		go serveMetrics(*metricsAddr, registry)
+	}
+
+	var s interface {
+		domain.EntryStreamer
+		streamer.Beater
+	}
+
+	start, err := startPositions()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *mode {
+	case "cursor":
+		live := &streamer.RedisStreamer{
+			Repo:         repo,
+			Clock:        streamer.RealClock{},
+			HeartTimeout: *heartTimeout,
+			Start:        start,
+			Metrics:      registry,
+		}
+
+		s = live
+
+		if *replayArchive != "" {
+			if start == nil {
+				log.Fatal("Replaying the archive needs --start-from or --start-time")
+			}
+
+			s = &archive.Replayer{
+				Backend: archive.FileBackend{Dir: *replayArchive},
+				Live:    live,
+				Start:   start,
+			}
+		}
+	case "group":
+		if start != nil || *replayArchive != "" {
+			log.Fatal("Replay is only supported in cursor mode")
+		}
+
+		if len(splitList(*streams)) > 0 {
+			log.Fatal("Group mode reads a single stream, set it with --stream")
+		}
+
+		if *retention > 0 && *retentionSafe {
+			log.Fatal("Safe retention follows cursors only, disable it with --retention-safe=false in group mode")
+		}
+
+		name := uuid.NewV4().String()
+
+		s = &streamer.GroupStreamer{
+			Repo:         repo,
+			Clock:        streamer.RealClock{},
+			Group:        *group,
+			Consumer:     name,
+			HeartTimeout: *heartTimeout,
+			Metrics:      registry,
+		}
+
+		r := streamer.Reclaimer{
+			Repo:          repo,
+			Group:         *group,
+			Consumer:      name,
+			IdleThreshold: *reclaimIdle,
+			MaxClaims:     *reclaimMax,
+			Interval:      *reclaimInterval,
+		}
+
+		go r.Run(ctx)
+	default:
+		log.Fatalf("Unknown consuming mode: %s", *mode)
+	}
+
+	if *retention > 0 {
+		t := streamer.Trimmer{
+			Repo:     repo,
+			Clock:    streamer.RealClock{},
+			Streams:  readStreams(),
+			MaxAge:   *retention,
+			Safe:     *retentionSafe,
+			Interval: *retentionInterval,
+			Metrics:  registry,
+		}
+
+		go t.Run(ctx)
+	}
+
+	heartbeat := streamer.Heartbeat{
 		Streamer: s,
+		Interval: *heartInterval,
+	}
+
+	go heartbeat.Run(ctx)
+
+	c, err := newConsumer()
+
+	if err != nil {
+		log.Fatal("Handler configuration error:", err)
+	}
+
+	runner := consumer.Runner{
+		Streamer:    s,
+		Consumer:    c,
+		DeadLetters: repo,
+		MaxRetries:  *maxRetries,
+		Backoff:     *backoff,
+		Metrics:     registry,
+
+		ShutdownTimeout: *shutdownTimeout,
+	}
+
+	err = runner.Run(ctx)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Consumer stopped")
+}
+
+//startPositions returns the positions a new cursor starts at on each of the read streams,
+//nil if it should read new entries only.
+func startPositions() (map[string]string, error) {
+	if *startFrom != "" && *startTime != "" {
+		return nil, errors.New("Only one of --start-from and --start-time can be given")
+	}
+
+	var position string
+
+	switch {
+	case *startFrom != "":
+		p, err := streamer.StartFrom(*startFrom)
+
+		if err != nil {
+			return nil, err
+		}
+
+		position = p
+	case *startTime != "":
+		t, err := time.Parse(time.RFC3339, *startTime)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid start time: %s", err)
+		}
+
+		position = streamer.StartTime(t)
+	default:
+		return nil, nil
+	}
+
+	return streamer.StartPositions(readStreams(), position), nil
+}
+
+//readStreams returns the names of the streams the consumer reads.
+func readStreams() []string {
+	read := splitList(*streams)
+
+	if len(read) == 0 {
+		return []string{*stream}
+	}
+
+	return read
+}
+
+//serveMetrics serves the metrics for scraping until the process exits.
+func serveMetrics(addr string, registry *metrics.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+
+	err := http.ListenAndServe(addr, mux)
+
+	if err != nil {
+		log.Printf("Error serving metrics: %s", err)
+	}
+}
+
+func newConsumer() (domain.EntryConsumer, error) {
+	if *config == "" {
+		return consumer.NewHandler(*handler, nil)
+	}
+
+	c, err := consumer.LoadConfig(*config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return consumer.NewRegistry(c)
+}
+
+//splitList splits a comma separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
 
-	log.Fatal(c.StartConsuming())
+	return items
 }
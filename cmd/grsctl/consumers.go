@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/storage"
+)
+
+func consumers(ctx context.Context, repo storage.RedisRepository, command string, args []string) error {
+	switch command {
+	case "list":
+		cursors, err := repo.GetCursors(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, c := range cursors {
+			fmt.Printf("%s\t%s\tepoch %d\t%s\n", c.Name, cursorState(c.HasHeart), c.Epoch, formatPositions(c.Positions))
+		}
+
+		return nil
+	case "lag":
+		health, err := repo.GetStreamHealth(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, h := range health {
+			fmt.Printf("%s\t%d entries\tlast %s\n", h.Stream, h.Length, h.LastID)
+
+			for _, c := range h.Consumers {
+				entries := fmt.Sprintf("%d", c.Entries)
+
+				if c.EntriesTruncated {
+					entries += "+"
+				}
+
+				lag := time.Duration(c.LagMillis) * time.Millisecond
+
+				fmt.Printf("  %s\t%s\t%s\t%s entries behind\t%s\n", c.Consumer, cursorState(c.Alive), c.Position, entries, lag)
+			}
+		}
+
+		return nil
+	case "evict":
+		fs := flag.NewFlagSet("consumers evict", flag.ExitOnError)
+		force := fs.Bool("force", false, "Evict the cursor even if its consumer is alive, the consumer is fenced out")
+		fs.Parse(args)
+
+		if fs.NArg() != 1 {
+			return fmt.Errorf("consumers evict expects exactly one name")
+		}
+
+		return cursorError(fs.Arg(0), repo.EvictCursor(ctx, fs.Arg(0), *force))
+	default:
+		return fmt.Errorf("unknown consumers command: %s", command)
+	}
+}
+
+func cursor(ctx context.Context, repo storage.RedisRepository, command string, args []string) error {
+	switch command {
+	case "set":
+		fs := flag.NewFlagSet("cursor set", flag.ExitOnError)
+		force := fs.Bool("force", false, "Set the position even if the consumer is alive, the consumer is fenced out")
+		fs.Parse(args)
+
+		if fs.NArg() < 2 || fs.NArg() > 3 {
+			return fmt.Errorf("cursor set expects a name, an ID and optionally a stream")
+		}
+
+		on := *stream
+
+		if fs.NArg() == 3 {
+			on = fs.Arg(2)
+		}
+
+		return cursorError(fs.Arg(0), repo.SetCursorPosition(ctx, fs.Arg(0), on, fs.Arg(1), *force))
+	default:
+		return fmt.Errorf("unknown cursor command: %s", command)
+	}
+}
+
+//cursorError explains the errors returned when changing a cursor.
+func cursorError(name string, err error) error {
+	switch err {
+	case domain.ErrNotFound:
+		return fmt.Errorf("no cursor named %s", name)
+	case domain.ErrCursorAlive:
+		return fmt.Errorf("consumer %s is alive, stop it or use --force", name)
+	default:
+		return err
+	}
+}
+
+func cursorState(alive bool) string {
+	if alive {
+		return "alive"
+	}
+
+	return "dead"
+}
+
+//formatPositions lists the positions of a cursor sorted by stream name.
+func formatPositions(positions map[string]string) string {
+	items := make([]string, 0, len(positions))
+
+	for stream, ID := range positions {
+		items = append(items, stream+"="+ID)
+	}
+
+	sort.Strings(items)
+
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/antekresic/grs/storage"
+	"github.com/go-redis/redis"
+)
+
+var (
+	redisAddr = flag.String("redis-address", ":6379", "Redis address")
+
+	stream           = flag.String("stream", storage.DefaultStream, "Name of the entries stream")
+	deadLetterStream = flag.String("dlq-stream", storage.DefaultDeadLetterStream, "Name of the dead letter queue stream")
+	namespace        = flag.String("namespace", "", "Prefix of all the Redis keys, used to run independent pipelines on one Redis")
+	route            = flag.String("route", "", "Name template of the stream published entries are added to, like events:"+storage.RouteObjectType)
+	streams          = flag.String("streams", "", "Comma separated streams reported on, the entries stream if empty")
+	rules            = flag.String("rules", "", "JSON file with the validation rules for published entries")
+	schemaDir        = flag.String("schema-dir", "", "Directory of the JSON Schemas the meta of published versioned entries is validated against")
+)
+
+const usage = `Usage: grsctl [options] <command> [arguments]
+
+Commands:
+  dlq list [--start=ID] [--count=N]   list entries in the dead letter queue
+  dlq inspect <id>                    show a single dead letter queue entry
+  dlq replay <id>...                  add entries back to the stream and remove them from the queue
+  dlq purge [id]...                   remove entries from the queue, all of them if no IDs are given
+  consumers list                      list the cursors with their positions, dead ones included
+  consumers lag                       show how far behind the last entry each consumer is, dead ones included
+  consumers evict [--force] <name>    remove a cursor, live ones only with --force
+  cursor set [--force] <name> <id> [stream]
+                                      set the last entry processed by a cursor on a stream, the entries stream if not given
+  stream info [stream]...             show the length and last ID of the streams and the dead letter queue
+  stream range [--from=ID] [--to=ID] [--count=N] [--reverse] [filters]
+                                      list entries of the stream
+  stream tail [--from=ID] [filters]   print entries as they are added to the stream until interrupted
+  publish [--batch=N] <file>...       add entries from NDJSON files, - reads standard input
+
+Options:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: *redisAddr,
+	})
+
+	_, err := redisClient.Ping().Result()
+
+	if err != nil {
+		log.Fatal("Redis connection error:", err)
+	}
+
+	repo := storage.RedisRepository{
+		Client: redisClient,
+		Config: storage.Config{
+			Stream:           *stream,
+			DeadLetterStream: *deadLetterStream,
+			Namespace:        *namespace,
+			Route:            *route,
+			Subscriptions:    splitList(*streams),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//Interrupting stops long running commands like the tail cleanly.
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		cancel()
+	}()
+
+	switch flag.Arg(0) {
+	case "dlq":
+		err = dlq(ctx, repo, flag.Arg(1), flag.Args()[2:])
+	case "consumers":
+		err = consumers(ctx, repo, flag.Arg(1), flag.Args()[2:])
+	case "cursor":
+		err = cursor(ctx, repo, flag.Arg(1), flag.Args()[2:])
+	case "stream":
+		err = streamCommand(ctx, repo, flag.Arg(1), flag.Args()[2:])
+	case "publish":
+		err = publish(ctx, repo, flag.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command: %s", flag.Arg(0))
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func dlq(ctx context.Context, repo storage.RedisRepository, command string, args []string) error {
+	switch command {
+	case "list":
+		fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+		start := fs.String("start", "-", "ID of the first entry to list")
+		count := fs.Int64("count", 100, "Maximum number of entries to list")
+		fs.Parse(args)
+
+		entries, err := repo.GetDeadEntries(ctx, *start, *count)
+
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			fmt.Printf("%s\t%s\t%d attempts\t%s\n", e.ID, e.OriginalID, len(e.Attempts), e.Reason)
+		}
+
+		return nil
+	case "inspect":
+		if len(args) != 1 {
+			return fmt.Errorf("dlq inspect expects exactly one ID")
+		}
+
+		entry, err := repo.GetDeadEntry(ctx, args[0])
+
+		if err != nil {
+			return err
+		}
+
+		return printJSON(entry)
+	case "replay":
+		if len(args) == 0 {
+			return fmt.Errorf("dlq replay expects at least one ID")
+		}
+
+		for _, ID := range args {
+			err := repo.ReplayDeadEntry(ctx, ID)
+
+			if err != nil {
+				return fmt.Errorf("replaying %s: %s", ID, err)
+			}
+		}
+
+		return nil
+	case "purge":
+		return repo.PurgeDeadEntries(ctx, args...)
+	default:
+		return fmt.Errorf("unknown dlq command: %s", command)
+	}
+}
+
+//splitList splits a comma separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func printJSON(v interface{}) error {
+	contents, err := json.MarshalIndent(v, "", "    ")
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(contents))
+
+	return nil
+}
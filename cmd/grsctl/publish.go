@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antekresic/grs/check"
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/schema"
+	"github.com/antekresic/grs/storage"
+	"github.com/go-playground/validator"
+)
+
+//maxLineSize is the longest line accepted in a published file
+const maxLineSize int = 1 << 20
+
+//publish adds the entries of NDJSON files to the stream, one entry per line.
+//Every line of a file is validated before any of them is added, so a bad line publishes nothing.
+func publish(ctx context.Context, repo storage.RedisRepository, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	batch := fs.Int("batch", 1000, "Number of entries added in a single round trip")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("publish expects at least one file")
+	}
+
+	if *batch <= 0 {
+		return fmt.Errorf("publish batch must be positive")
+	}
+
+	v := check.All{
+		check.Entry{
+			Validator: validator.New(),
+		},
+	}
+
+	if *rules != "" {
+		entryRules, err := check.LoadRules(*rules)
+
+		if err != nil {
+			return err
+		}
+
+		v = append(v, entryRules)
+	}
+
+	if *schemaDir != "" {
+		registry := &schema.Registry{Dir: *schemaDir}
+
+		err := registry.Load()
+
+		if err != nil {
+			return fmt.Errorf("loading schemas: %s", err)
+		}
+
+		v = append(v, schema.Validator{Registry: registry})
+	}
+
+	for _, name := range fs.Args() {
+		entries, err := readEntries(ctx, v, name)
+
+		if err != nil {
+			return err
+		}
+
+		created, duplicates := 0, 0
+
+		for start := 0; start < len(entries); start += *batch {
+			end := start + *batch
+
+			if end > len(entries) {
+				end = len(entries)
+			}
+
+			results, err := repo.AddEntries(ctx, entries[start:end])
+
+			if err != nil {
+				return fmt.Errorf("%s: published %d of %d entries: %s", name, created+duplicates, len(entries), err)
+			}
+
+			for i, result := range results {
+				switch {
+				case result.Err != nil:
+					return fmt.Errorf("%s: entry %d not published: %s", name, start+i+1, result.Err)
+				case result.Created:
+					created++
+				default:
+					duplicates++
+				}
+			}
+		}
+
+		fmt.Printf("%s\t%d entries published, %d published before\n", name, created, duplicates)
+	}
+
+	return nil
+}
+
+//readEntries reads and validates the entries of a NDJSON file, - is the standard input.
+func readEntries(ctx context.Context, v domain.EntryValidator, name string) ([]domain.Entry, error) {
+	var r io.Reader = os.Stdin
+
+	if name != "-" {
+		f, err := os.Open(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+		r = f
+	}
+
+	entries := []domain.Entry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		content := bytes.TrimSpace(scanner.Bytes())
+
+		if len(content) == 0 {
+			continue
+		}
+
+		var e domain.Entry
+		err := json.Unmarshal(content, &e)
+
+		if err == nil {
+			err = v.Validate(ctx, e)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", name, line, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	err := scanner.Err()
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	return entries, nil
+}
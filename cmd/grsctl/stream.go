@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/storage"
+)
+
+//tailCount is the maximum number of entries read at once by the tail
+const tailCount int64 = 100
+
+func streamCommand(ctx context.Context, repo storage.RedisRepository, command string, args []string) error {
+	switch command {
+	case "info":
+		names := args
+
+		if len(names) == 0 {
+			names = repo.Config.Subscriptions
+		}
+
+		if len(names) == 0 {
+			names = []string{*stream}
+		}
+
+		for _, name := range names {
+			info, err := repo.GetStreamInfo(ctx, name)
+
+			if err != nil {
+				return err
+			}
+
+			printInfo(info)
+		}
+
+		info, err := repo.GetDeadLetterInfo(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		printInfo(info)
+
+		return nil
+	case "range":
+		fs := flag.NewFlagSet("stream range", flag.ExitOnError)
+		q := queryFlags(fs)
+		fs.Int64Var(&q.Count, "count", 100, "Maximum number of entries to list")
+		fs.StringVar(&q.To, "to", "", "ID of the last entry to list, the newest if empty")
+		fs.BoolVar(&q.Reverse, "reverse", false, "List the newest entries first")
+		fs.Parse(args)
+
+		page, err := repo.QueryEntries(ctx, *q)
+
+		if err != nil {
+			return err
+		}
+
+		err = printEntries(page.Entries)
+
+		if err != nil {
+			return err
+		}
+
+		if page.Next != "" {
+			fmt.Fprintf(os.Stderr, "next: %s\n", page.Next)
+		}
+
+		return nil
+	case "tail":
+		fs := flag.NewFlagSet("stream tail", flag.ExitOnError)
+		q := queryFlags(fs)
+		fs.Parse(args)
+
+		q.Count = tailCount
+
+		for {
+			page, err := repo.TailEntries(ctx, *q)
+
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			err = printEntries(page.Entries)
+
+			if err != nil {
+				return err
+			}
+
+			q.From = page.Next
+		}
+	default:
+		return fmt.Errorf("unknown stream command: %s", command)
+	}
+}
+
+//queryFlags adds the flags shared by the range and the tail to the flag set.
+func queryFlags(fs *flag.FlagSet) *domain.EntryQuery {
+	q := &domain.EntryQuery{}
+
+	fs.StringVar(&q.From, "from", "", "ID to start from, the oldest entry for range and the newest for tail if empty")
+	fs.IntVar(&q.ObjectID, "object-id", 0, "Only entries with the object ID")
+	fs.IntVar(&q.ObjectType, "object-type", 0, "Only entries with the object type, read from its stream when routed")
+	fs.StringVar(&q.Action, "action", "", "Only entries with the action")
+
+	return q
+}
+
+func printInfo(info domain.StreamInfo) {
+	fmt.Printf("%s\t%d entries\tlast %s\n", info.Name, info.Length, info.LastID)
+}
+
+//printEntries prints a line with the ID and the JSON of every entry,
+//the second column can be published again.
+func printEntries(entries []domain.Entry) error {
+	for _, e := range entries {
+		content, err := json.Marshal(e)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\t%s\n", e.ID, content)
+	}
+
+	return nil
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/antekresic/grs/check"
+	"github.com/antekresic/grs/metrics"
+	"github.com/antekresic/grs/schema"
 	"github.com/antekresic/grs/server"
 	"github.com/antekresic/grs/storage"
 	"github.com/go-playground/validator"
@@ -16,11 +24,35 @@ import (
 var (
 	port      = flag.Int("port", 80, "HTTP port for the service")
 	redisAddr = flag.String("redis-address", ":6379", "Redis address")
+
+	stream           = flag.String("stream", storage.DefaultStream, "Name of the entries stream")
+	deadLetterStream = flag.String("dlq-stream", storage.DefaultDeadLetterStream, "Name of the dead letter queue stream")
+	namespace        = flag.String("namespace", "", "Prefix of all the Redis keys, used to run independent pipelines on one Redis")
+	route            = flag.String("route", "", "Name template of the stream entries are added to, like events:"+storage.RouteObjectType)
+	streams          = flag.String("streams", "", "Comma separated streams searched when fetching entries by ID, required with --route, the entries stream if empty")
+
+	shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "Time allowed for in-flight requests to finish on shutdown")
+	idempotencyTTL  = flag.Duration("idempotency-ttl", storage.DefaultIdempotencyTTL, "How long idempotency keys are remembered")
+	maxLen          = flag.Int64("max-len", 0, "Approximate number of entries a stream is trimmed to on every add, streams are not trimmed when 0. Ignores safe retention cursors and archiver holds, entries may be removed before they are consumed or archived")
+
+	rules        = flag.String("rules", "", "JSON file with the validation rules for entries")
+	schemaDir    = flag.String("schema-dir", "", "Directory of the JSON Schemas the meta of versioned entries is validated against")
+	schemaReload = flag.Duration("schema-reload", schema.DefaultReloadInterval, "How often the schema directory is checked for changes")
 )
 
 func main() {
 	flag.Parse()
 
+	//Routed entries are never added to the entries stream, they can only be fetched from the listed streams.
+	if *route != "" && *streams == "" {
+		log.Fatal("--streams listing the streams entries are routed to is required with --route")
+	}
+
+	//XADD MAXLEN trims blindly, neither the cursors of safe retention nor the archiver holds are looked at.
+	if *maxLen > 0 {
+		log.Printf("--max-len trims streams to about %d entries regardless of consumer positions and archiver holds", *maxLen)
+	}
+
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: *redisAddr,
 	})
@@ -32,17 +64,109 @@ func main() {
 	}
 
 	r := storage.RedisRepository{
-		Client: redisClient,
+		Client:         redisClient,
+		IdempotencyTTL: *idempotencyTTL,
+		MaxLen:         *maxLen,
+		Config: storage.Config{
+			Stream:           *stream,
+			DeadLetterStream: *deadLetterStream,
+			Namespace:        *namespace,
+			Route:            *route,
+			Subscriptions:    splitList(*streams),
+		},
 	}
 
-	v := check.Entry{
-		Validator: validator.New(),
+	v := check.All{
+		check.Entry{
+			Validator: validator.New(),
+		},
 	}
 
+	if *rules != "" {
+		entryRules, err := check.LoadRules(*rules)
+
+		if err != nil {
+			log.Fatal("Error loading rules:", err)
+		}
+
+		v = append(v, entryRules)
+	}
+
+	if *schemaDir != "" {
+		registry := &schema.Registry{Dir: *schemaDir}
+
+		err = registry.Load()
+
+		if err != nil {
+			log.Fatal("Error loading schemas:", err)
+		}
+
+		log.Printf("Loaded %d schemas from %s", registry.Len(), *schemaDir)
+
+		go registry.Watch(context.Background(), *schemaReload)
+
+		v = append(v, schema.Validator{Registry: registry})
+	}
+
+	done := make(chan struct{})
+
 	s := server.HTTP{
-		Repo:      &r,
-		Validator: v,
+		Repo:        &r,
+		Validator:   v,
+		DeadLetters: r,
+		Health:      r,
+		Done:        done,
+		Metrics:     metrics.NewRegistry(),
+	}
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: s,
+	}
+
+	//Long-lived requests like the live tail are not finished by Shutdown on their own.
+	srv.RegisterOnShutdown(func() {
+		close(done)
+	})
+
+	stopped := make(chan struct{})
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		log.Printf("Received %s, shutting down", <-signals)
+
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
+
+		if err != nil {
+			log.Printf("Error shutting down: %s", err)
+		}
+
+		close(stopped)
+	}()
+
+	err = srv.ListenAndServe()
+
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	<-stopped
+	log.Println("Publisher stopped")
+}
+
+//splitList splits a comma separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
 
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), s))
+	return items
 }
@@ -1,20 +1,18 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/antekresic/grs/domain"
 )
 
 //Printer consumes stream entries by printing them to stdout
-type Printer struct {
-	Streamer domain.EntryStreamer
-}
+type Printer struct{}
 
 //Consume prints the entry to stdout
-func (p Printer) Consume(e domain.Entry) error {
+func (p Printer) Consume(ctx context.Context, e domain.Entry) error {
 	contents, err := json.MarshalIndent(e, "", "    ")
 
 	if err != nil {
@@ -26,29 +24,10 @@ func (p Printer) Consume(e domain.Entry) error {
 	return err
 }
 
-//StartConsuming consumes all the entries it gets from entry repo
-func (p Printer) StartConsuming() error {
-	for {
-		entries, err := p.Streamer.GetEntries()
-
-		if err != nil {
-			return err
-		}
-
-		for _, e := range entries {
-			err = p.Consume(e)
+//Discard consumes stream entries by ignoring them
+type Discard struct{}
 
-			if err != nil {
-				log.Println(err.Error())
-				continue
-			}
-
-			err = p.Streamer.MarkEntryProcessed(e.ID)
-
-			if err != nil {
-				log.Println(err.Error())
-				continue
-			}
-		}
-	}
+//Consume does nothing with the entry
+func (d Discard) Consume(ctx context.Context, e domain.Entry) error {
+	return nil
 }
@@ -0,0 +1,19 @@
+package consumer
+
+//countConsumed counts an entry consumed successfully.
+func (r Runner) countConsumed() {
+	if r.Metrics == nil {
+		return
+	}
+
+	r.Metrics.Counter("grs_entries_consumed_total", "Entries consumed successfully").Inc()
+}
+
+//countConsumeErrors counts the failed attempts of consuming an entry.
+func (r Runner) countConsumeErrors(failed int) {
+	if r.Metrics == nil {
+		return
+	}
+
+	r.Metrics.Counter("grs_consume_errors_total", "Failed attempts of consuming an entry").Add(float64(failed))
+}
@@ -0,0 +1,141 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/antekresic/grs/domain"
+)
+
+//HandlerFactory creates a handler using the options from the configuration
+type HandlerFactory func(options map[string]string) (domain.EntryConsumer, error)
+
+var factories = map[string]HandlerFactory{
+	"printer": func(map[string]string) (domain.EntryConsumer, error) {
+		return Printer{}, nil
+	},
+	"discard": func(map[string]string) (domain.EntryConsumer, error) {
+		return Discard{}, nil
+	},
+	"webhook": newWebhook,
+}
+
+//RegisterHandler makes the handler available by name in the configuration.
+//Registering a name twice replaces the previous handler.
+func RegisterHandler(name string, f HandlerFactory) {
+	factories[name] = f
+}
+
+//NewHandler creates a handler registered under the name
+func NewHandler(name string, options map[string]string) (domain.EntryConsumer, error) {
+	f, ok := factories[name]
+
+	if !ok {
+		return nil, fmt.Errorf("NewHandler: unknown handler %s", name)
+	}
+
+	return f(options)
+}
+
+//routeKey identifies the entries a handler is registered for.
+//Zero values match any object type or action.
+type routeKey struct {
+	objectType int
+	action     string
+}
+
+//Registry is an entry consumer which routes entries to the handlers
+//registered for their object type and action
+type Registry struct {
+	handlers map[routeKey]domain.EntryConsumer
+}
+
+//Register sets the handler for entries with the object type and action.
+//Use 0 as object type or empty action to match any of them.
+func (r *Registry) Register(objectType int, action string, c domain.EntryConsumer) {
+	if r.handlers == nil {
+		r.handlers = make(map[routeKey]domain.EntryConsumer)
+	}
+
+	r.handlers[routeKey{objectType, action}] = c
+}
+
+//Consume passes the entry to the most specific handler registered for it.
+//Entries without a handler are skipped.
+func (r Registry) Consume(ctx context.Context, e domain.Entry) error {
+	c := r.handler(e)
+
+	if c == nil {
+		return nil
+	}
+
+	return c.Consume(ctx, e)
+}
+
+func (r Registry) handler(e domain.Entry) domain.EntryConsumer {
+	keys := []routeKey{
+		{e.ObjectType, e.Action},
+		{e.ObjectType, ""},
+		{0, e.Action},
+		{0, ""},
+	}
+
+	for _, k := range keys {
+		if c, ok := r.handlers[k]; ok {
+			return c
+		}
+	}
+
+	return nil
+}
+
+//RouteConfig configures the handler for entries with the object type and action
+type RouteConfig struct {
+	ObjectType int               `json:"object_type"`
+	Action     string            `json:"action"`
+	Handler    string            `json:"handler"`
+	Options    map[string]string `json:"options"`
+}
+
+//Config holds the configuration of the handler registry
+type Config struct {
+	Routes []RouteConfig `json:"routes"`
+}
+
+//LoadConfig reads the registry configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var c Config
+
+	contents, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return c, fmt.Errorf("LoadConfig: %s", err)
+	}
+
+	err = json.Unmarshal(contents, &c)
+
+	if err != nil {
+		return c, fmt.Errorf("LoadConfig: %s", err)
+	}
+
+	return c, nil
+}
+
+//NewRegistry creates the registry with the handlers from the configuration
+func NewRegistry(c Config) (*Registry, error) {
+	r := &Registry{}
+
+	for _, route := range c.Routes {
+		h, err := NewHandler(route.Handler, route.Options)
+
+		if err != nil {
+			return nil, fmt.Errorf("NewRegistry: %s", err)
+		}
+
+		r.Register(route.ObjectType, route.Action, h)
+	}
+
+	return r, nil
+}
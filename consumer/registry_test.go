@@ -0,0 +1,83 @@
+package consumer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingConsumer struct {
+	entries []domain.Entry
+}
+
+func (r *recordingConsumer) Consume(ctx context.Context, e domain.Entry) error {
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	exact := &recordingConsumer{}
+	byType := &recordingConsumer{}
+	byAction := &recordingConsumer{}
+
+	r := Registry{}
+	r.Register(1, "create", exact)
+	r.Register(1, "", byType)
+	r.Register(0, "delete", byAction)
+
+	entries := []domain.Entry{
+		domain.Entry{ObjectType: 1, Action: "create"},
+		domain.Entry{ObjectType: 1, Action: "delete"},
+		domain.Entry{ObjectType: 2, Action: "delete"},
+		domain.Entry{ObjectType: 2, Action: "update"},
+	}
+
+	for _, e := range entries {
+		assert.Nil(t, r.Consume(context.Background(), e), "Error is not nil")
+	}
+
+	assert.Equal(t, entries[0:1], exact.entries, "Exact handler got wrong entries")
+	assert.Equal(t, entries[1:2], byType.entries, "Object type handler got wrong entries")
+	assert.Equal(t, entries[2:3], byAction.entries, "Action handler got wrong entries")
+
+	fallback := &recordingConsumer{}
+	r.Register(0, "", fallback)
+
+	assert.Nil(t, r.Consume(context.Background(), entries[3]), "Error is not nil")
+	assert.Equal(t, entries[3:4], fallback.entries, "Fallback handler got wrong entries")
+}
+
+func TestNewRegistry(t *testing.T) {
+	t.Run("Load config file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "registry")
+		require.Nil(t, err, "Error creating temp dir")
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "handlers.json")
+		config := `{"routes":[{"object_type":1,"handler":"printer"},{"handler":"discard"}]}`
+		require.Nil(t, ioutil.WriteFile(path, []byte(config), 0644), "Error writing config")
+
+		c, err := LoadConfig(path)
+
+		require.Nil(t, err, "Error is not nil")
+		require.Len(t, c.Routes, 2, "Wrong number of routes")
+
+		r, err := NewRegistry(c)
+
+		require.Nil(t, err, "Error is not nil")
+		assert.Equal(t, Printer{}, r.handler(domain.Entry{ObjectType: 1}), "Wrong handler for object type")
+		assert.Equal(t, Discard{}, r.handler(domain.Entry{ObjectType: 2}), "Wrong fallback handler")
+	})
+
+	t.Run("Unknown handler", func(t *testing.T) {
+		_, err := NewRegistry(Config{Routes: []RouteConfig{RouteConfig{Handler: "unknown"}}})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
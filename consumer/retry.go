@@ -0,0 +1,56 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//DefaultMaxRetries is the number of times consuming an entry is retried before it is dead-lettered
+	DefaultMaxRetries int = 3
+	//DefaultBackoff is the wait before the first retry, it doubles with each next retry
+	DefaultBackoff time.Duration = 100 * time.Millisecond
+)
+
+//sleep waits for the duration or until the context is done, it is replaced in tests to avoid waiting for backoffs.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+//consumeWithRetries consumes the entry until it succeeds, fails permanently or it runs out of retries.
+//Retries wait for the backoff or as long as the error asks, see RetryAfter.
+//Returns the history of failed attempts and the last error, or the context error if it is done while waiting.
+func consumeWithRetries(ctx context.Context, c domain.EntryConsumer, e domain.Entry, maxRetries int, backoff time.Duration) ([]domain.Attempt, error) {
+	attempts := []domain.Attempt{}
+
+	for i := 0; ; i++ {
+		err := c.Consume(ctx, e)
+
+		if err == nil {
+			return attempts, nil
+		}
+
+		attempts = append(attempts, domain.Attempt{
+			Time:  time.Now(),
+			Error: err.Error(),
+		})
+
+		if i >= maxRetries || IsPermanent(err) {
+			return attempts, err
+		}
+
+		if err := sleep(ctx, retryWait(err, backoff<<uint(i))); err != nil {
+			return attempts, err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingConsumer struct {
+	failures int
+	calls    int
+}
+
+func (f *failingConsumer) Consume(ctx context.Context, e domain.Entry) error {
+	f.calls++
+
+	if f.calls <= f.failures {
+		return errors.New("some error")
+	}
+
+	return nil
+}
+
+type permanentConsumer struct{}
+
+func (p permanentConsumer) Consume(ctx context.Context, e domain.Entry) error {
+	return Permanent(errors.New("some error"))
+}
+
+type rateLimitedConsumer struct {
+	calls int
+}
+
+func (r *rateLimitedConsumer) Consume(ctx context.Context, e domain.Entry) error {
+	r.calls++
+
+	if r.calls == 1 {
+		return RetryAfter(errors.New("some error"), 5*time.Second)
+	}
+
+	return nil
+}
+
+func TestConsumeWithRetries(t *testing.T) {
+	var waits []time.Duration
+
+	defaultSleep := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	defer func() { sleep = defaultSleep }()
+
+	t.Run("Succeed after retries", func(t *testing.T) {
+		waits = nil
+		c := &failingConsumer{failures: 2}
+
+		attempts, err := consumeWithRetries(context.Background(), c, domain.Entry{}, 3, time.Second)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 3, c.calls, "Consumed wrong number of times")
+		assert.Len(t, attempts, 2, "Wrong number of failed attempts")
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits, "Wrong backoff")
+	})
+
+	t.Run("Run out of retries", func(t *testing.T) {
+		waits = nil
+		c := &failingConsumer{failures: 10}
+
+		attempts, err := consumeWithRetries(context.Background(), c, domain.Entry{}, 2, time.Second)
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Equal(t, 3, c.calls, "Consumed wrong number of times")
+		assert.Len(t, attempts, 3, "Wrong number of failed attempts")
+		assert.Equal(t, "some error", attempts[2].Error, "Wrong attempt error")
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits, "Wrong backoff")
+	})
+
+	t.Run("Permanent error", func(t *testing.T) {
+		waits = nil
+		c := &permanentConsumer{}
+
+		attempts, err := consumeWithRetries(context.Background(), c, domain.Entry{}, 3, time.Second)
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Len(t, attempts, 1, "Retried a permanent error")
+		assert.Empty(t, waits, "Waited without retrying")
+	})
+
+	t.Run("Wait as long as the error asks", func(t *testing.T) {
+		waits = nil
+		c := &rateLimitedConsumer{}
+
+		_, err := consumeWithRetries(context.Background(), c, domain.Entry{}, 3, time.Second)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []time.Duration{5 * time.Second}, waits, "Wrong wait")
+	})
+
+	t.Run("No retries", func(t *testing.T) {
+		waits = nil
+		c := &failingConsumer{failures: 1}
+
+		attempts, err := consumeWithRetries(context.Background(), c, domain.Entry{}, 0, time.Second)
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Equal(t, 1, c.calls, "Consumed wrong number of times")
+		assert.Len(t, attempts, 1, "Wrong number of failed attempts")
+		assert.Empty(t, waits, "Waited without retrying")
+	})
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := sleep(ctx, time.Hour)
+
+	assert.Equal(t, context.Canceled, err, "Did not stop waiting when the context is done")
+	assert.Nil(t, sleep(context.Background(), time.Millisecond), "Error is not nil")
+}
@@ -0,0 +1,187 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+)
+
+//DefaultShutdownTimeout is how long the entry being processed may take to finish after the shutdown when none is specified
+const DefaultShutdownTimeout time.Duration = 10 * time.Second
+
+//Runner feeds the entries from the streamer to the consumer
+type Runner struct {
+	Streamer    domain.EntryStreamer
+	Consumer    domain.EntryConsumer
+	DeadLetters domain.DeadLetterRepository
+	MaxRetries  int
+	Backoff     time.Duration
+
+	//ShutdownTimeout is how long the entry being processed may take to finish once the context is done.
+	//Retries and waits still running then are cut short and the entry is left for the next consumer.
+	ShutdownTimeout time.Duration
+
+	//Metrics record the consumed entries and failed attempts when set.
+	Metrics *metrics.Registry
+}
+
+//Run consumes all the entries it gets from the streamer until the context is done.
+//The entry being processed when the context is done is finished and marked
+//before the streamer is released, unless it takes longer than the shutdown timeout.
+//An entry which is neither consumed nor dead-lettered stops the runner, so no later entry
+//is marked and the position of the streamer stays before it.
+func (r Runner) Run(ctx context.Context) error {
+	//Processing an entry is only interrupted once the shutdown timeout passed.
+	processCtx, cancel := shutdownContext(ctx, r.shutdownTimeout())
+	defer cancel()
+
+	//Consumed entries are marked and the streamer is released whatever the timeout.
+	detached := context.WithoutCancel(ctx)
+
+	var failed error
+
+read:
+	for ctx.Err() == nil {
+		entries, err := r.Streamer.GetEntries(ctx)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				break
+			}
+
+			err = r.process(processCtx, e)
+
+			if err != nil && processCtx.Err() != nil {
+				log.Printf("Processing entry %s was interrupted by the shutdown: %s\n", e.ID, err)
+				break read
+			}
+
+			if err != nil {
+				failed = fmt.Errorf("Run: entry %s was neither consumed nor dead-lettered: %s", e.ID, err)
+				break read
+			}
+
+			err = r.Streamer.MarkEntryProcessed(detached, e.ID)
+
+			//The rest of the entries belong to the consumer which took over.
+			if domain.IsFenced(err) {
+				log.Println(err.Error())
+				break
+			}
+
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+		}
+	}
+
+	err := r.Streamer.Release(detached)
+
+	if failed != nil {
+		return failed
+	}
+
+	return err
+}
+
+//process consumes the entry with retries and moves it to the dead letter queue if all of them fail.
+//Interrupted attempts counted by the streamer use up retries, see domain.AttemptCounter.
+func (r Runner) process(ctx context.Context, e domain.Entry) error {
+	previous := r.previousAttempts(ctx, e.ID)
+
+	var attempts []domain.Attempt
+	var err error
+
+	if previous > r.MaxRetries {
+		attempts = []domain.Attempt{}
+		err = fmt.Errorf("processing was interrupted %d times", previous)
+	} else {
+		attempts, err = consumeWithRetries(ctx, r.Consumer, e, r.MaxRetries-previous, r.Backoff<<uint(previous))
+		r.countConsumeErrors(len(attempts))
+	}
+
+	if err == nil {
+		r.countConsumed()
+		return nil
+	}
+
+	//An entry cut short by the shutdown did not fail.
+	if r.DeadLetters == nil || ctx.Err() != nil {
+		return err
+	}
+
+	log.Printf("Entry %s failed after %d attempts, dead-lettering it: %s\n", e.ID, previous+len(attempts), err)
+
+	return r.deadLetter(ctx, e, err.Error(), attempts)
+}
+
+//deadLetter moves the entry to the dead letter queue, failed writes are retried with the backoff.
+func (r Runner) deadLetter(ctx context.Context, e domain.Entry, reason string, attempts []domain.Attempt) error {
+	for i := 0; ; i++ {
+		err := r.DeadLetters.DeadLetter(ctx, e, reason, attempts)
+
+		if err == nil || i >= r.MaxRetries {
+			return err
+		}
+
+		log.Printf("Dead-lettering entry %s failed, retrying: %s\n", e.ID, err)
+
+		if err := sleep(ctx, r.Backoff<<uint(i)); err != nil {
+			return err
+		}
+	}
+}
+
+func (r Runner) shutdownTimeout() time.Duration {
+	if r.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+
+	return r.ShutdownTimeout
+}
+
+//shutdownContext returns a context which is cancelled the timeout after the parent context is done.
+func shutdownContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+
+	stop := context.AfterFunc(parent, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+//previousAttempts returns how many times processing the entry was started before if the streamer counts it.
+//Retries start over when the count is not available.
+func (r Runner) previousAttempts(ctx context.Context, ID string) int {
+	counter, ok := r.Streamer.(domain.AttemptCounter)
+
+	if !ok {
+		return 0
+	}
+
+	previous, err := counter.CountAttempt(ctx, ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return 0
+	}
+
+	return previous
+}
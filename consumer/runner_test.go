@@ -0,0 +1,274 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+//cancellingStreamer returns its entries once and cancels the context when the first one is marked.
+type cancellingStreamer struct {
+	entries  []domain.Entry
+	cancel   context.CancelFunc
+	marked   []string
+	released bool
+}
+
+func (s *cancellingStreamer) GetEntries(ctx context.Context) ([]domain.Entry, error) {
+	entries := s.entries
+	s.entries = nil
+	return entries, nil
+}
+
+func (s *cancellingStreamer) MarkEntryProcessed(ctx context.Context, ID string) error {
+	s.marked = append(s.marked, ID)
+	s.cancel()
+	return ctx.Err()
+}
+
+func (s *cancellingStreamer) Release(ctx context.Context) error {
+	s.released = true
+	return nil
+}
+
+//fencedStreamer is fenced out of its cursor when the first entry is marked.
+type fencedStreamer struct {
+	cancellingStreamer
+	calls int
+}
+
+func (s *fencedStreamer) GetEntries(ctx context.Context) ([]domain.Entry, error) {
+	s.calls++
+
+	//Cancel on the read after the fenced batch.
+	if s.calls > 1 {
+		s.cancel()
+	}
+
+	return s.cancellingStreamer.GetEntries(ctx)
+}
+
+func (s *fencedStreamer) MarkEntryProcessed(ctx context.Context, ID string) error {
+	s.marked = append(s.marked, ID)
+	return &domain.FencedError{Name: "someName"}
+}
+
+//countingStreamer reports earlier attempts at its entries.
+type countingStreamer struct {
+	cancellingStreamer
+	previous int
+	counted  []string
+}
+
+func (s *countingStreamer) CountAttempt(ctx context.Context, ID string) (int, error) {
+	s.counted = append(s.counted, ID)
+	return s.previous, nil
+}
+
+type deadLetters struct {
+	domain.DeadLetterRepository
+	entries []domain.Entry
+	calls   int
+	err     error
+}
+
+func (d *deadLetters) DeadLetter(ctx context.Context, e domain.Entry, reason string, attempts []domain.Attempt) error {
+	d.calls++
+
+	if d.err != nil {
+		return d.err
+	}
+
+	d.entries = append(d.entries, e)
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("Finish current entry on shutdown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &cancellingStreamer{
+			entries: []domain.Entry{domain.Entry{ID: "1-0"}, domain.Entry{ID: "2-0"}},
+			cancel:  cancel,
+		}
+		c := &recordingConsumer{}
+
+		err := Runner{Streamer: s, Consumer: c}.Run(ctx)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []string{"1-0"}, s.marked, "Wrong entries marked")
+		assert.Len(t, c.entries, 1, "Wrong entries consumed")
+		assert.True(t, s.released, "Streamer not released")
+	})
+
+	t.Run("Dead-letter failed entry", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &cancellingStreamer{
+			entries: []domain.Entry{domain.Entry{ID: "1-0"}},
+			cancel:  cancel,
+		}
+		d := &deadLetters{}
+
+		err := Runner{Streamer: s, Consumer: permanentConsumer{}, DeadLetters: d}.Run(ctx)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []string{"1-0"}, s.marked, "Dead-lettered entry not marked")
+		assert.Len(t, d.entries, 1, "Entry not dead-lettered")
+	})
+}
+
+func TestRunnerUnhandledEntry(t *testing.T) {
+	t.Run("Dead letter error keeps the position before the entry", func(t *testing.T) {
+		s := &cancellingStreamer{
+			entries: []domain.Entry{domain.Entry{ID: "1-0"}, domain.Entry{ID: "2-0"}},
+			cancel:  func() {},
+		}
+		c := &failingConsumer{failures: 10}
+		d := &deadLetters{err: errors.New("some error")}
+
+		err := Runner{Streamer: s, Consumer: c, DeadLetters: d, MaxRetries: 2}.Run(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, s.marked, "Entries marked after an entry which was not dead-lettered")
+		assert.Equal(t, 3, c.calls, "Kept consuming after an entry which was not dead-lettered")
+		assert.Equal(t, 3, d.calls, "Dead letter write not retried")
+		assert.True(t, s.released, "Streamer not released")
+	})
+
+	t.Run("No dead letter queue", func(t *testing.T) {
+		s := &cancellingStreamer{
+			entries: []domain.Entry{domain.Entry{ID: "1-0"}, domain.Entry{ID: "2-0"}},
+			cancel:  func() {},
+		}
+
+		err := Runner{Streamer: s, Consumer: permanentConsumer{}}.Run(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, s.marked, "Entries marked after an entry which was not dead-lettered")
+	})
+}
+
+//blockingConsumer shuts the runner down once it starts and blocks until its context is done.
+type blockingConsumer struct {
+	shutdown context.CancelFunc
+}
+
+func (b blockingConsumer) Consume(ctx context.Context, e domain.Entry) error {
+	b.shutdown()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunnerShutdownTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &cancellingStreamer{
+		entries: []domain.Entry{domain.Entry{ID: "1-0"}},
+		cancel:  cancel,
+	}
+	d := &deadLetters{}
+
+	err := Runner{
+		Streamer:        s,
+		Consumer:        blockingConsumer{shutdown: cancel},
+		DeadLetters:     d,
+		ShutdownTimeout: time.Millisecond,
+	}.Run(ctx)
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Empty(t, s.marked, "Interrupted entry marked")
+	assert.Zero(t, d.calls, "Interrupted entry dead-lettered")
+	assert.True(t, s.released, "Streamer not released")
+}
+
+func TestShutdownContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, stop := shutdownContext(parent, 10*time.Millisecond)
+	defer stop()
+
+	cancel()
+	assert.Nil(t, ctx.Err(), "Cancelled together with the parent")
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Not cancelled after the timeout")
+	}
+}
+
+func TestRunnerAttempts(t *testing.T) {
+	t.Run("Earlier attempts use up retries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &countingStreamer{
+			cancellingStreamer: cancellingStreamer{entries: []domain.Entry{domain.Entry{ID: "1-0"}}, cancel: cancel},
+			previous:           2,
+		}
+		c := &failingConsumer{failures: 5}
+		d := &deadLetters{}
+
+		err := Runner{Streamer: s, Consumer: c, DeadLetters: d, MaxRetries: 3}.Run(ctx)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []string{"1-0"}, s.counted, "Attempt not counted")
+		assert.Equal(t, 2, c.calls, "Retries started over")
+		assert.Len(t, d.entries, 1, "Entry not dead-lettered")
+	})
+
+	t.Run("Dead-letter entry interrupted too often without consuming it", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &countingStreamer{
+			cancellingStreamer: cancellingStreamer{entries: []domain.Entry{domain.Entry{ID: "1-0"}}, cancel: cancel},
+			previous:           4,
+		}
+		c := &failingConsumer{}
+		d := &deadLetters{}
+
+		err := Runner{Streamer: s, Consumer: c, DeadLetters: d, MaxRetries: 3}.Run(ctx)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 0, c.calls, "Entry consumed after running out of attempts")
+		assert.Len(t, d.entries, 1, "Entry not dead-lettered")
+		assert.Equal(t, []string{"1-0"}, s.marked, "Dead-lettered entry not marked")
+	})
+}
+
+func TestRunnerMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &cancellingStreamer{
+		entries: []domain.Entry{domain.Entry{ID: "1-0"}},
+		cancel:  cancel,
+	}
+	registry := metrics.NewRegistry()
+
+	err := Runner{Streamer: s, Consumer: permanentConsumer{}, DeadLetters: &deadLetters{}, Metrics: registry}.Run(ctx)
+
+	assert.Nil(t, err, "Error is not nil")
+
+	var b bytes.Buffer
+	registry.WriteTo(&b)
+
+	assert.Contains(t, b.String(), "grs_consume_errors_total 1\n", "Consume errors not counted")
+	assert.NotContains(t, b.String(), "grs_entries_consumed_total 1", "Failed entry counted as consumed")
+}
+
+func TestRunnerFenced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &fencedStreamer{
+		cancellingStreamer: cancellingStreamer{
+			entries: []domain.Entry{domain.Entry{ID: "1-0"}, domain.Entry{ID: "2-0"}},
+			cancel:  cancel,
+		},
+	}
+	c := &recordingConsumer{}
+
+	err := Runner{Streamer: s, Consumer: c}.Run(ctx)
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Equal(t, []string{"1-0"}, s.marked, "Kept processing entries after being fenced out")
+	assert.Len(t, c.entries, 1, "Wrong entries consumed")
+	assert.Equal(t, 2, s.calls, "Did not read again after being fenced out")
+}
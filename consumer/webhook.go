@@ -0,0 +1,447 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//SignatureHeader holds the HMAC-SHA256 signature of the timestamp and the body
+	SignatureHeader string = "X-Grs-Signature"
+	//TimestampHeader holds the unix time of the request, part of the signed payload
+	TimestampHeader string = "X-Grs-Timestamp"
+	//EntryIDHeader holds the stream ID of the entry so receivers can deduplicate
+	EntryIDHeader string = "X-Grs-Entry-Id"
+
+	//DefaultWebhookTimeout is the timeout of a single webhook request
+	DefaultWebhookTimeout time.Duration = 10 * time.Second
+	//DefaultFailureThreshold is the number of consecutive failures which opens the circuit
+	DefaultFailureThreshold int = 5
+	//DefaultCooldown is how long the circuit stays open before a request is let through
+	DefaultCooldown time.Duration = 30 * time.Second
+
+	//maxRetryAfter limits how long a Retry-After header can hold back the next attempt
+	maxRetryAfter time.Duration = 1 * time.Minute
+)
+
+//Webhook consumes entries by posting them to an HTTP endpoint.
+//Network errors, 408, 429 and 5xx responses are retried with an exponential backoff,
+//waiting at least as long as the Retry-After header asks. Other non-2xx responses fail the entry right away.
+type Webhook struct {
+	URL        string
+	Secret     string
+	Client     *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+	Breaker    *CircuitBreaker
+}
+
+//Consume posts the entry to the webhook URL
+func (w Webhook) Consume(ctx context.Context, e domain.Entry) error {
+	body, err := json.Marshal(struct {
+		ID string `json:"id"`
+		domain.Entry
+	}{e.ID, e})
+
+	if err != nil {
+		return Permanent(err)
+	}
+
+	for i := 0; ; i++ {
+		if w.Breaker != nil {
+			err = w.Breaker.Wait(ctx)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err = w.post(ctx, e.ID, body)
+
+		if w.Breaker != nil {
+			w.record(ctx, err)
+		}
+
+		if err == nil || IsPermanent(err) || i >= w.MaxRetries {
+			return err
+		}
+
+		if err := sleep(ctx, retryWait(err, w.Backoff<<uint(i))); err != nil {
+			return err
+		}
+	}
+}
+
+//record registers the outcome of the request with the circuit breaker.
+//Requests cut short by the context say nothing about the destination.
+func (w Webhook) record(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		w.Breaker.release()
+		return
+	}
+
+	w.Breaker.Record(err == nil || IsPermanent(err))
+}
+
+func (w Webhook) post(ctx context.Context, ID string, body []byte) error {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req = req.WithContext(ctx)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EntryIDHeader, ID)
+	req.Header.Set(TimestampHeader, timestamp)
+
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, timestamp, body))
+	}
+
+	client := w.Client
+
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("Webhook: %s", err)
+	}
+
+	//Drain the body so the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	err = fmt.Errorf("Webhook: %s responded with %d", w.URL, resp.StatusCode)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return RetryAfter(err, wait)
+		}
+
+		return err
+	default:
+		return Permanent(err)
+	}
+}
+
+//parseRetryAfter reads the Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+
+	date, err := http.ParseTime(value)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Until(date), true
+}
+
+//Sign computes the signature sent in the SignatureHeader.
+//Receivers verify it by computing the HMAC-SHA256 of "{timestamp}.{body}" with the shared secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//CircuitBreaker stops requests to a destination after too many consecutive failures.
+//While the circuit is open callers wait for the cooldown to pass. Then a single trial request
+//is let through while the others wait for its outcome, which closes the circuit on success
+//and opens it again on failure.
+type CircuitBreaker struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu       sync.Mutex
+	failures int
+	//openUntil is the end of the cooldown, zero while the circuit is closed
+	openUntil time.Time
+	//trial is set while the trial request is in flight, trialDone is closed once it is over
+	trial     bool
+	trialDone chan struct{}
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*CircuitBreaker{}
+)
+
+//BreakerFor returns the circuit breaker shared by all the webhooks posting to the URL
+func BreakerFor(URL string, failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[URL]
+
+	if !ok {
+		b = &CircuitBreaker{
+			FailureThreshold: failureThreshold,
+			Cooldown:         cooldown,
+		}
+		breakers[URL] = b
+	}
+
+	return b
+}
+
+//Wait blocks until a request may be sent: right away while the circuit is closed,
+//as the trial request once the cooldown passed or after the trial request closed the circuit.
+//Returns the context error if the context is done first.
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+
+		var timer *time.Timer
+		var trialDone <-chan struct{}
+		var expired <-chan time.Time
+
+		switch wait := time.Until(b.openUntil); {
+		case b.openUntil.IsZero():
+			b.mu.Unlock()
+			return nil
+		case wait > 0:
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		case b.trial:
+			trialDone = b.trialDone
+		default:
+			b.trial = true
+			b.trialDone = make(chan struct{})
+			b.mu.Unlock()
+			return nil
+		}
+
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+
+			return ctx.Err()
+		case <-expired:
+		case <-trialDone:
+		}
+	}
+}
+
+//Record registers the outcome of a request. The outcome of the trial request closes or opens
+//the circuit, otherwise the circuit is opened once the failure threshold is reached.
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.trial {
+		b.endTrial()
+
+		if success {
+			b.openUntil = time.Time{}
+		} else {
+			b.openUntil = time.Now().Add(b.Cooldown)
+		}
+
+		return
+	}
+
+	if success {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.FailureThreshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+		b.failures = 0
+	}
+}
+
+//release ends the trial without an outcome so another request is let through as the trial.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.trial {
+		b.endTrial()
+	}
+}
+
+func (b *CircuitBreaker) endTrial() {
+	b.trial = false
+	b.failures = 0
+	close(b.trialDone)
+}
+
+//IsOpen reports if requests are currently being held back
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.openUntil.IsZero() && (time.Now().Before(b.openUntil) || b.trial)
+}
+
+//retryAfterError asks for the next attempt to wait at least the given time
+type retryAfterError struct {
+	err  error
+	wait time.Duration
+}
+
+func (r retryAfterError) Error() string {
+	return r.err.Error()
+}
+
+//RetryAfter marks the error as one which should not be retried before the wait is over
+func RetryAfter(err error, wait time.Duration) error {
+	return retryAfterError{err, wait}
+}
+
+//retryWait returns how long to wait before retrying after the error: the backoff,
+//or the time the error asks for if it is longer, up to maxRetryAfter.
+func retryWait(err error, backoff time.Duration) time.Duration {
+	var r retryAfterError
+
+	if !errors.As(err, &r) || r.wait <= backoff {
+		return backoff
+	}
+
+	if r.wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+
+	return r.wait
+}
+
+//permanentError marks errors which won't go away by retrying
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string {
+	return p.err.Error()
+}
+
+//Permanent marks the error as one which should not be retried
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+//IsPermanent checks if the error should not be retried
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+//newWebhook creates the webhook handler from the registry options.
+//Retries are left to the Runner by default so they aren't multiplied.
+func newWebhook(options map[string]string) (domain.EntryConsumer, error) {
+	w := Webhook{
+		URL:    options["url"],
+		Secret: options["secret"],
+	}
+
+	if w.URL == "" {
+		return nil, errors.New("webhook url option is required")
+	}
+
+	timeout, err := durationOption(options, "timeout", DefaultWebhookTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.Client = &http.Client{Timeout: timeout}
+
+	w.Backoff, err = durationOption(options, "backoff", DefaultBackoff)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cooldown, err := durationOption(options, "cooldown", DefaultCooldown)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.MaxRetries, err = intOption(options, "max_retries", 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	threshold, err := intOption(options, "failure_threshold", DefaultFailureThreshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.Breaker = BreakerFor(w.URL, threshold, cooldown)
+
+	return w, nil
+}
+
+func durationOption(options map[string]string, name string, def time.Duration) (time.Duration, error) {
+	v, ok := options[name]
+
+	if !ok {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s option: %s", name, err)
+	}
+
+	return d, nil
+}
+
+func intOption(options map[string]string, name string, def int) (int, error) {
+	v, ok := options[name]
+
+	if !ok {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s option: %s", name, err)
+	}
+
+	return i, nil
+}
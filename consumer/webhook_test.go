@@ -0,0 +1,250 @@
+package consumer
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook(t *testing.T) {
+	var waits []time.Duration
+
+	defaultSleep := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	defer func() { sleep = defaultSleep }()
+
+	t.Run("Signed request", func(t *testing.T) {
+		var req *http.Request
+		var body []byte
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req = r
+			body, _ = ioutil.ReadAll(r.Body)
+		}))
+		defer server.Close()
+
+		w := Webhook{URL: server.URL, Secret: "secret"}
+
+		err := w.Consume(context.Background(), domain.Entry{ID: "1-0", ObjectID: 3, Action: "create"})
+
+		require.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "1-0", req.Header.Get(EntryIDHeader), "Entry ID header not correct")
+		assert.Equal(
+			t,
+			Sign("secret", req.Header.Get(TimestampHeader), body),
+			req.Header.Get(SignatureHeader),
+			"Signature not correct",
+		)
+		assert.Contains(t, string(body), `"id":"1-0"`, "Body does not contain the ID")
+	})
+
+	t.Run("Retry server errors", func(t *testing.T) {
+		waits = nil
+		calls := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			if calls < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		w := Webhook{URL: server.URL, MaxRetries: 3, Backoff: time.Second}
+
+		err := w.Consume(context.Background(), domain.Entry{ID: "1-0"})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 3, calls, "Wrong number of requests")
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits, "Wrong backoff")
+	})
+
+	t.Run("Client errors are permanent", func(t *testing.T) {
+		calls := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}))
+		defer server.Close()
+
+		w := Webhook{URL: server.URL, MaxRetries: 3}
+
+		err := w.Consume(context.Background(), domain.Entry{ID: "1-0"})
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.True(t, IsPermanent(err), "Error is not permanent")
+		assert.Equal(t, 1, calls, "Retried a client error")
+	})
+
+	t.Run("Retry timeouts and rate limits", func(t *testing.T) {
+		for _, status := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests} {
+			calls := 0
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				if calls == 1 {
+					w.WriteHeader(status)
+				}
+			}))
+
+			w := Webhook{URL: server.URL, MaxRetries: 1, Backoff: time.Second}
+
+			err := w.Consume(context.Background(), domain.Entry{ID: "1-0"})
+			server.Close()
+
+			assert.Nil(t, err, "Error is not nil for %d", status)
+			assert.Equal(t, 2, calls, "Did not retry %d", status)
+		}
+	})
+
+	t.Run("Honour Retry-After", func(t *testing.T) {
+		waits = nil
+		calls := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			switch calls {
+			case 1:
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		w := Webhook{URL: server.URL, MaxRetries: 2, Backoff: time.Second}
+
+		err := w.Consume(context.Background(), domain.Entry{ID: "1-0"})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []time.Duration{7 * time.Second, maxRetryAfter}, waits, "Retry-After not honoured")
+	})
+
+	t.Run("Open circuit after failures", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		breaker := &CircuitBreaker{FailureThreshold: 2, Cooldown: time.Hour}
+		w := Webhook{URL: server.URL, MaxRetries: 1, Breaker: breaker}
+
+		err := w.Consume(context.Background(), domain.Entry{ID: "1-0"})
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.True(t, breaker.IsOpen(), "Circuit is not open")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = w.Consume(ctx, domain.Entry{ID: "2-0"})
+
+		assert.Equal(t, context.DeadlineExceeded, err, "Did not stop waiting when the context was done")
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	open := func() *CircuitBreaker {
+		b := &CircuitBreaker{FailureThreshold: 1, Cooldown: time.Millisecond}
+		b.Record(false)
+
+		return b
+	}
+
+	t.Run("Closed circuit", func(t *testing.T) {
+		b := &CircuitBreaker{FailureThreshold: 2, Cooldown: time.Hour}
+		b.Record(false)
+
+		assert.False(t, b.IsOpen(), "Circuit opened before the threshold")
+		assert.Nil(t, b.Wait(context.Background()), "Error is not nil")
+	})
+
+	t.Run("Single trial request after the cooldown", func(t *testing.T) {
+		b := open()
+
+		require.Nil(t, b.Wait(context.Background()), "Trial request not let through")
+		assert.True(t, b.IsOpen(), "Circuit closed before the trial request succeeded")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.Equal(t, context.DeadlineExceeded, b.Wait(ctx), "Second request let through during the trial")
+	})
+
+	t.Run("Successful trial closes the circuit", func(t *testing.T) {
+		b := open()
+		require.Nil(t, b.Wait(context.Background()), "Trial request not let through")
+
+		waiting := make(chan error)
+		go func() { waiting <- b.Wait(context.Background()) }()
+
+		b.Record(true)
+
+		assert.Nil(t, <-waiting, "Waiting request not let through")
+		assert.False(t, b.IsOpen(), "Circuit not closed")
+	})
+
+	t.Run("Failed trial opens the circuit", func(t *testing.T) {
+		b := open()
+		b.Cooldown = time.Hour
+		require.Nil(t, b.Wait(context.Background()), "Trial request not let through")
+
+		b.Record(false)
+
+		assert.True(t, b.IsOpen(), "Circuit not opened again")
+	})
+
+	t.Run("Released trial lets another request through", func(t *testing.T) {
+		b := open()
+		require.Nil(t, b.Wait(context.Background()), "Trial request not let through")
+
+		b.release()
+
+		assert.Nil(t, b.Wait(context.Background()), "Next trial request not let through")
+	})
+}
+
+func TestNewWebhook(t *testing.T) {
+	t.Run("Missing url", func(t *testing.T) {
+		_, err := NewHandler("webhook", map[string]string{})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		h, err := NewHandler("webhook", map[string]string{
+			"url":     "http://localhost/hook",
+			"timeout": "2s",
+		})
+
+		require.Nil(t, err, "Error is not nil")
+
+		w := h.(Webhook)
+
+		assert.Equal(t, 2*time.Second, w.Client.Timeout, "Timeout not correct")
+		assert.Equal(t, 0, w.MaxRetries, "Retries not left to the runner")
+		assert.Equal(t, BreakerFor("http://localhost/hook", 0, 0), w.Breaker, "Breaker not shared")
+	})
+
+	t.Run("Invalid option", func(t *testing.T) {
+		_, err := NewHandler("webhook", map[string]string{"url": "http://localhost", "timeout": "soon"})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
@@ -1,43 +1,344 @@
 package domain
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ErrNotFound is returned when the requested item does not exist
+var ErrNotFound = errors.New("not found")
+
+//ErrUnavailable is returned when the storage cannot be reached, the request can be retried later
+var ErrUnavailable = errors.New("storage unavailable")
+
+//ErrCursorAlive is returned when a cursor is changed while its consumer is still alive
+var ErrCursorAlive = errors.New("cursor is alive")
+
+//FencedError is returned when a cursor is written with an epoch which was superseded,
+//meaning another consumer has taken over the cursor.
+type FencedError struct {
+	Name  string
+	Epoch int64
+}
+
+func (e *FencedError) Error() string {
+	return fmt.Sprintf("cursor %s fenced out at epoch %d", e.Name, e.Epoch)
+}
+
+//IsFenced reports whether the error is a FencedError or wraps one
+func IsFenced(err error) bool {
+	var fenced *FencedError
+	return errors.As(err, &fenced)
+}
+
 //Entry represents an entry in the event stream
 type Entry struct {
 	ID         string `json:"-"`
+	Stream     string `json:"-"`
 	ObjectID   int    `json:"object_id" validate:"required"`
 	ObjectType int    `json:"object_type" validate:"required"`
 	Action     string `json:"action" validate:"oneof=create update delete"`
-	Meta       string `json:"meta" validate:"eq=JSON"`
+
+	//Meta is the JSON payload of the entry, checked against the schema of the schema version when one is given
+	Meta          json.RawMessage `json:"meta,omitempty"`
+	SchemaVersion int             `json:"schema_version,omitempty" validate:"min=0"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+//CompareIDs compares two stream IDs, returning -1, 0 or 1 if a is lower than, equal to or greater than b.
+//IDs without the sequence part are treated as having sequence 0.
+func CompareIDs(a, b string) (int, error) {
+	aMillis, aSeq, err := ParseID(a)
+
+	if err != nil {
+		return 0, err
+	}
+
+	bMillis, bSeq, err := ParseID(b)
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case aMillis < bMillis || (aMillis == bMillis && aSeq < bSeq):
+		return -1, nil
+	case aMillis == bMillis && aSeq == bSeq:
+		return 0, nil
+	default:
+		return 1, nil
+	}
+}
+
+//ParseID splits a stream ID into its milliseconds and sequence parts.
+//IDs without the sequence part have sequence 0.
+func ParseID(ID string) (millis uint64, seq uint64, err error) {
+	parts := strings.SplitN(ID, "-", 2)
+
+	millis, err = strconv.ParseUint(parts[0], 10, 64)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream ID %q", ID)
+	}
+
+	if len(parts) == 2 {
+		seq, err = strconv.ParseUint(parts[1], 10, 64)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid stream ID %q", ID)
+		}
+	}
+
+	return millis, seq, nil
+}
+
+//NextID returns the smallest possible stream ID greater than the given one
+//or empty string if the ID is not valid.
+func NextID(ID string) string {
+	millis, seq, err := ParseID(ID)
+
+	if err != nil {
+		return ""
+	}
+
+	if seq == math.MaxUint64 {
+		return fmt.Sprintf("%d-0", millis+1)
+	}
+
+	return fmt.Sprintf("%d-%d", millis, seq+1)
+}
+
+//PreviousID returns the greatest possible stream ID smaller than the given one
+//or empty string if there is none or the ID is not valid.
+func PreviousID(ID string) string {
+	millis, seq, err := ParseID(ID)
+
+	if err != nil || (millis == 0 && seq == 0) {
+		return ""
+	}
+
+	if seq == 0 {
+		return fmt.Sprintf("%d-%d", millis-1, uint64(math.MaxUint64))
+	}
+
+	return fmt.Sprintf("%d-%d", millis, seq-1)
+}
+
+//EntryQuery describes a range of entries to fetch from the stream.
+//Zero values of the filter fields match every entry.
+type EntryQuery struct {
+	From       string
+	To         string
+	Count      int64
+	Reverse    bool
+	ObjectID   int
+	ObjectType int
+	Action     string
+}
+
+//EntryPage is a page of entries matching a query.
+//Next is the ID to continue from or empty if there are no more entries.
+type EntryPage struct {
+	Entries []Entry
+	Next    string
 }
 
 //StreamCursor holds information about stream consumer last location
 type StreamCursor struct {
-	Name         string
-	LastID       string
+	Name string
+
+	//Positions maps the names of the streams to the last IDs processed on them
+	Positions    map[string]string
 	HeartTimeout int64
 	HasHeart     bool
+
+	//Epoch is the fencing token of the cursor, incremented every time the cursor is taken over
+	Epoch int64
+}
+
+//AddResult is the outcome of adding a single entry of a batch.
+//Created is false for entries whose idempotency key was already used, ID is then the one of the original entry.
+//Err is set if the entry was not added.
+type AddResult struct {
+	ID      string
+	Created bool
+	Err     error
 }
 
 //EntryRepository is an interface for persisting entries
 type EntryRepository interface {
-	AddEntry(Entry) error
-	GetEntries(lastID string) (entries []Entry, newLastID string, err error)
-	StoreCursor(StreamCursor) error
-	GetCursors() (cursors []StreamCursor, err error)
-	StealCursor(oldCursor StreamCursor, newName string) error
+	AddEntry(ctx context.Context, e Entry) (ID string, err error)
+	AddEntryOnce(ctx context.Context, e Entry) (ID string, created bool, err error)
+	GetEntry(ctx context.Context, ID string) (Entry, error)
+	QueryEntries(ctx context.Context, q EntryQuery) (EntryPage, error)
+	TailEntries(ctx context.Context, q EntryQuery) (EntryPage, error)
+	AddEntries(ctx context.Context, entries []Entry) (results []AddResult, err error)
+	GetEntries(ctx context.Context, positions map[string]string) (entries []Entry, newPositions map[string]string, err error)
+	StoreCursor(ctx context.Context, cursor StreamCursor) error
+	GetCursors(ctx context.Context) (cursors []StreamCursor, err error)
+	StealCursor(ctx context.Context, oldCursor StreamCursor, newName string) error
+	ReleaseCursor(ctx context.Context, name string) error
+	RefreshCursor(ctx context.Context, cursor StreamCursor) error
+	CountAttempt(ctx context.Context, name, stream, ID string) (previous int, err error)
+}
+
+//RetentionRepository is an interface for removing old entries from the streams
+type RetentionRepository interface {
+	GetCursors(ctx context.Context) (cursors []StreamCursor, err error)
+	TrimEntries(ctx context.Context, stream, maxID string) (trimmed int64, err error)
+	GetHolds(ctx context.Context, stream string) (holds map[string]string, err error)
+}
+
+//ArchiveRepository is an interface for copying a stream into an archive
+type ArchiveRepository interface {
+	QueryEntries(ctx context.Context, q EntryQuery) (EntryPage, error)
+	HoldEntries(ctx context.Context, stream, name, ID string) error
+}
+
+//StreamInfo holds information about a stream
+type StreamInfo struct {
+	Name   string
+	Length int64
+
+	//LastID is the ID of the last entry ever added, even if it was removed since
+	LastID string
+}
+
+//MonitorRepository is an interface for inspecting the streams and the cursors reading them
+type MonitorRepository interface {
+	GetCursors(ctx context.Context) (cursors []StreamCursor, err error)
+	GetStreamInfo(ctx context.Context, stream string) (StreamInfo, error)
+	GetDeadLetterInfo(ctx context.Context) (StreamInfo, error)
+}
+
+//ConsumerLag holds how far a cursor is behind the last entry of a stream
+type ConsumerLag struct {
+	Consumer string `json:"consumer"`
+	Alive    bool   `json:"alive"`
+	Position string `json:"position"`
+
+	//Entries is the number of entries added after the position, counted up to a limit
+	Entries int64 `json:"entries"`
+	//EntriesTruncated is set when there are more entries after the position than were counted
+	EntriesTruncated bool `json:"entries_truncated,omitempty"`
+	//LagMillis is the time between adding the entry at the position and the last entry of the stream
+	LagMillis int64 `json:"lag_ms"`
+}
+
+//StreamHealth holds the state of a stream and the lag of the cursors reading it
+type StreamHealth struct {
+	Stream    string        `json:"stream"`
+	Length    int64         `json:"length"`
+	LastID    string        `json:"last_id"`
+	Consumers []ConsumerLag `json:"consumers"`
+}
+
+//HealthRepository is an interface for reporting the health of the streams
+type HealthRepository interface {
+	GetStreamHealth(ctx context.Context) ([]StreamHealth, error)
+}
+
+//PendingEntry holds information about an entry delivered to a group consumer but not yet acknowledged
+type PendingEntry struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	Deliveries int64
+}
+
+//GroupRepository is an interface for consuming entries through a consumer group
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, group string) error
+	GetGroupEntries(ctx context.Context, group, consumer, lastID string) ([]Entry, error)
+	AckEntry(ctx context.Context, group, ID string) error
+	GetPendingEntries(ctx context.Context, group, start string, count int64) ([]PendingEntry, error)
+	ClaimEntries(ctx context.Context, group, consumer string, minIdle time.Duration, IDs []string) ([]Entry, error)
+	StoreHeart(ctx context.Context, consumer string, timeout time.Duration) error
+	HasHeart(ctx context.Context, consumer string) (bool, error)
+	RemoveHeart(ctx context.Context, consumer string) error
+}
+
+//Attempt records a single failed attempt of processing an entry
+type Attempt struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+//DeadEntry is an entry which could not be processed and was moved to the dead letter queue
+type DeadEntry struct {
+	ID         string            `json:"id"`
+	OriginalID string            `json:"original_id"`
+	Stream     string            `json:"stream,omitempty"`
+	Reason     string            `json:"reason"`
+	Attempts   []Attempt         `json:"attempts"`
+	Values     map[string]string `json:"values"`
+}
+
+//DeadLetterRepository is an interface for managing the dead letter queue
+type DeadLetterRepository interface {
+	DeadLetter(ctx context.Context, e Entry, reason string, attempts []Attempt) error
+	GetDeadEntries(ctx context.Context, start string, count int64) ([]DeadEntry, error)
+	GetDeadEntry(ctx context.Context, ID string) (DeadEntry, error)
+	ReplayDeadEntry(ctx context.Context, ID string) error
+	PurgeDeadEntries(ctx context.Context, IDs ...string) error
 }
 
 //EntryStreamer streams entries and marks them as processed
 type EntryStreamer interface {
-	GetEntries() ([]Entry, error)
-	MarkEntryProcessed(ID string) error
+	GetEntries(ctx context.Context) ([]Entry, error)
+	MarkEntryProcessed(ctx context.Context, ID string) error
+	Release(ctx context.Context) error
+}
+
+//AttemptCounter is implemented by streamers which remember how many times processing an entry was started,
+//so the retries of an entry are not started over when its processing is interrupted.
+type AttemptCounter interface {
+	CountAttempt(ctx context.Context, ID string) (previous int, err error)
 }
 
 //EntryValidator is an interface for validating entries
 type EntryValidator interface {
-	Validate(Entry) error
+	Validate(ctx context.Context, e Entry) error
+}
+
+//FieldError is a validation rule broken by a single field of an entry.
+//Path is the JSON pointer to the field, like /object_id or /meta/name.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+//ValidationError is returned by validators for entries which break their rules, listing every broken rule
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+
+	for i, f := range e.Errors {
+		messages[i] = f.Path + " " + f.Message
+	}
+
+	return "invalid entry: " + strings.Join(messages, "; ")
+}
+
+//AsValidationError returns the ValidationError if the error is one or wraps one
+func AsValidationError(err error) (*ValidationError, bool) {
+	var invalid *ValidationError
+	ok := errors.As(err, &invalid)
+
+	return invalid, ok
 }
 
 //EntryConsumer consumes the entry
 type EntryConsumer interface {
-	Consume(Entry) error
+	Consume(ctx context.Context, e Entry) error
 }
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamIDs(t *testing.T) {
+	assert.Equal(t, "5-4", NextID("5-3"), "Next ID not correct")
+	assert.Equal(t, "6-0", NextID("5-18446744073709551615"), "Next ID not correct")
+	assert.Equal(t, "5-2", PreviousID("5-3"), "Previous ID not correct")
+	assert.Equal(t, "4-18446744073709551615", PreviousID("5-0"), "Previous ID not correct")
+	assert.Equal(t, "", PreviousID("0-0"), "Previous ID not empty")
+	assert.Equal(t, "", NextID("invalid"), "Next ID not empty")
+}
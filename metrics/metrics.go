@@ -0,0 +1,347 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	counterKind   string = "counter"
+	gaugeKind     string = "gauge"
+	histogramKind string = "histogram"
+
+	contentType    string = "text/plain; version=0.0.4; charset=utf-8"
+	labelSeparator string = "\xff"
+)
+
+//DefaultBuckets are the upper bounds in seconds of the histogram buckets used when none are given
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+//Registry holds the metrics and serves them in the Prometheus text format.
+//Metrics are created on first use, asking for a metric again returns the existing one.
+type Registry struct {
+	mu         sync.Mutex
+	families   []*family
+	byName     map[string]*family
+	collectors []func(ctx context.Context)
+}
+
+//family is a metric with all its label combinations.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+//series is a metric with one combination of label values.
+type series struct {
+	values []string
+	value  float64
+
+	//Histograms only
+	counts []uint64
+	count  uint64
+}
+
+//Counter is a metric which only goes up.
+type Counter struct {
+	f *family
+}
+
+//Gauge is a metric which can go up and down.
+type Gauge struct {
+	f *family
+}
+
+//Histogram counts observations in buckets.
+type Histogram struct {
+	f *family
+}
+
+//NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*family)}
+}
+
+//Counter returns the counter with the name, creating it with the help text and label names.
+func (r *Registry) Counter(name, help string, labels ...string) Counter {
+	return Counter{r.family(name, help, counterKind, labels, nil)}
+}
+
+//Gauge returns the gauge with the name, creating it with the help text and label names.
+func (r *Registry) Gauge(name, help string, labels ...string) Gauge {
+	return Gauge{r.family(name, help, gaugeKind, labels, nil)}
+}
+
+//Histogram returns the histogram with the name, creating it with the help text, bucket upper bounds and label names.
+//DefaultBuckets are used when no buckets are given.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	return Histogram{r.family(name, help, histogramKind, labels, buckets)}
+}
+
+//OnScrape adds a function which is called before the metrics are written,
+//used for metrics which are read from somewhere else instead of being recorded.
+func (r *Registry) OnScrape(collect func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, collect)
+}
+
+//ServeHTTP serves the metrics for scraping.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.collect(req.Context())
+
+	w.Header().Set("Content-Type", contentType)
+	r.WriteTo(w)
+}
+
+//WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := make([]*family, len(r.families))
+	copy(families, r.families)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	for _, f := range families {
+		f.write(cw)
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+
+	return cw.n, bw.Flush()
+}
+
+func (r *Registry) collect(ctx context.Context) {
+	r.mu.Lock()
+	collectors := make([]func(ctx context.Context), len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	for _, collect := range collectors {
+		collect(ctx)
+	}
+}
+
+func (r *Registry) family(name, help, kind string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.byName[name]; ok {
+		if f.kind != kind || len(f.labels) != len(labels) {
+			panic(fmt.Sprintf("metric %s registered again with a different type or labels", name))
+		}
+
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+
+	//Metrics without labels are reported from the start.
+	if len(labels) == 0 {
+		f.series[""] = &series{counts: make([]uint64, len(buckets))}
+	}
+
+	r.families = append(r.families, f)
+	r.byName[name] = f
+
+	return f
+}
+
+//Inc adds one to the counter with the label values.
+func (c Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+//Add adds a non-negative value to the counter with the label values.
+func (c Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+
+	c.f.update(values, func(s *series) {
+		s.value += v
+	})
+}
+
+//Set sets the gauge with the label values.
+func (g Gauge) Set(v float64, values ...string) {
+	g.f.update(values, func(s *series) {
+		s.value = v
+	})
+}
+
+//Add adds the value, which can be negative, to the gauge with the label values.
+func (g Gauge) Add(v float64, values ...string) {
+	g.f.update(values, func(s *series) {
+		s.value += v
+	})
+}
+
+//Reset removes all the label combinations of the gauge, so the ones which are gone are not reported anymore.
+func (g Gauge) Reset() {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+
+	g.f.series = make(map[string]*series)
+}
+
+//Observe records the value in the histogram with the label values.
+func (h Histogram) Observe(v float64, values ...string) {
+	h.f.update(values, func(s *series) {
+		for i, bound := range h.f.buckets {
+			if v <= bound {
+				s.counts[i]++
+			}
+		}
+
+		s.count++
+		s.value += v
+	})
+}
+
+func (f *family) update(values []string, fn func(s *series)) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+
+	key := strings.Join(values, labelSeparator)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+
+	if !ok {
+		s = &series{values: append([]string(nil), values...), counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+
+	fn(s)
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+
+		if f.kind != histogramKind {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+			continue
+		}
+
+		for i, bound := range f.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.values, formatFloat(bound)), s.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.values, formatFloat(math.Inf(1))), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelPairs(s.values, ""), s.count)
+	}
+}
+
+//labelPairs formats the labels of a series, le is added for histogram buckets when not empty.
+func (f *family) labelPairs(values []string, le string) string {
+	pairs := make([]string, 0, len(values)+1)
+
+	for i, v := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], escapeLabel(v)))
+	}
+
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+//countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+
+	return n, err
+}
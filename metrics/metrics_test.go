@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Write counters and gauges", func(t *testing.T) {
+		r := NewRegistry()
+
+		c := r.Counter("grs_test_total", "Test counter", "action")
+		c.Inc("create")
+		c.Add(2, "update")
+		c.Inc("create")
+		c.Add(-1, "create")
+
+		r.Gauge("grs_test_gauge", "Test gauge").Set(1.5)
+
+		var b bytes.Buffer
+		_, err := r.WriteTo(&b)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, `# HELP grs_test_total Test counter
+# TYPE grs_test_total counter
+grs_test_total{action="create"} 2
+grs_test_total{action="update"} 2
+# HELP grs_test_gauge Test gauge
+# TYPE grs_test_gauge gauge
+grs_test_gauge 1.5
+`, b.String(), "Output not correct")
+	})
+
+	t.Run("Write histograms", func(t *testing.T) {
+		r := NewRegistry()
+
+		h := r.Histogram("grs_test_seconds", "Test histogram", []float64{0.1, 1}, "op")
+		h.Observe(0.05, "add")
+		h.Observe(0.5, "add")
+		h.Observe(2, "add")
+
+		var b bytes.Buffer
+		_, err := r.WriteTo(&b)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, `# HELP grs_test_seconds Test histogram
+# TYPE grs_test_seconds histogram
+grs_test_seconds_bucket{op="add",le="0.1"} 1
+grs_test_seconds_bucket{op="add",le="1"} 2
+grs_test_seconds_bucket{op="add",le="+Inf"} 3
+grs_test_seconds_sum{op="add"} 2.55
+grs_test_seconds_count{op="add"} 3
+`, b.String(), "Output not correct")
+	})
+
+	t.Run("Metrics are created once", func(t *testing.T) {
+		r := NewRegistry()
+
+		r.Counter("grs_test_total", "Test counter").Inc()
+		r.Counter("grs_test_total", "Test counter").Inc()
+
+		var b bytes.Buffer
+		r.WriteTo(&b)
+
+		assert.Contains(t, b.String(), "grs_test_total 2\n", "Counter not shared")
+		assert.Panics(t, func() { r.Gauge("grs_test_total", "Test gauge") }, "Registered with another type")
+	})
+
+	t.Run("Escape label values and reset gauges", func(t *testing.T) {
+		r := NewRegistry()
+
+		g := r.Gauge("grs_test_gauge", "Test gauge", "name")
+		g.Set(1, "old")
+		g.Reset()
+		g.Set(2, "a \"quoted\"\nname")
+
+		var b bytes.Buffer
+		r.WriteTo(&b)
+
+		assert.NotContains(t, b.String(), "old", "Gauge not reset")
+		assert.Contains(t, b.String(), `grs_test_gauge{name="a \"quoted\"\nname"} 2`, "Label not escaped")
+	})
+
+	t.Run("Serve metrics after collecting", func(t *testing.T) {
+		r := NewRegistry()
+		r.OnScrape(func(ctx context.Context) {
+			r.Gauge("grs_test_gauge", "Test gauge").Set(7)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		require.Equal(t, 200, w.Code, "Status not correct")
+		assert.Equal(t, contentType, w.Header().Get("Content-Type"), "Content type not correct")
+		assert.Contains(t, w.Body.String(), "grs_test_gauge 7\n", "Collected metric missing")
+	})
+}
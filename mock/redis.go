@@ -0,0 +1,174 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+//TestRedisClient is a mock of the storage.RedisClient used for testing purposes
+type TestRedisClient struct {
+	XAddArgs                         *redis.XAddArgs
+	XAddReturnStringCmd              *redis.StringCmd
+	XReadArgs                        *redis.XReadArgs
+	XReadReturnXStreamSliceCmd       *redis.XStreamSliceCmd
+	TxPipelineReturnPipeliner        redis.Pipeliner
+	PipelineReturnPipeliner          redis.Pipeliner
+	SortSet                          string
+	SortSort                         *redis.Sort
+	SortReturnStringSliceCmd         *redis.StringSliceCmd
+	WatchKeys                        []string
+	WatchReturnError                 error
+	XReadGroupArgs                   *redis.XReadGroupArgs
+	XReadGroupReturnXStreamSliceCmd  *redis.XStreamSliceCmd
+	XAckStream                       string
+	XAckGroup                        string
+	XAckIDs                          []string
+	XAckReturnIntCmd                 *redis.IntCmd
+	XPendingExtArgs                  *redis.XPendingExtArgs
+	XPendingExtReturnXPendingExtCmd  *redis.XPendingExtCmd
+	XClaimArgs                       *redis.XClaimArgs
+	XClaimReturnXMessageSliceCmd     *redis.XMessageSliceCmd
+	DoArgs                           []interface{}
+	DoReturnCmd                      *redis.Cmd
+	SetKey                           string
+	SetValue                         interface{}
+	SetExpiration                    time.Duration
+	SetReturnStatusCmd               *redis.StatusCmd
+	ExistsKeys                       []string
+	ExistsReturnIntCmd               *redis.IntCmd
+	XRangeNStream                    string
+	XRangeNStart                     string
+	XRangeNStop                      string
+	XRangeNCount                     int64
+	XRangeNReturnXMessageSliceCmd    *redis.XMessageSliceCmd
+	XRevRangeNStream                 string
+	XRevRangeNStart                  string
+	XRevRangeNStop                   string
+	XRevRangeNCount                  int64
+	XRevRangeNReturnXMessageSliceCmd *redis.XMessageSliceCmd
+	DelKeys                          []string
+	DelReturnIntCmd                  *redis.IntCmd
+	EvalScript                       string
+	EvalKeys                         []string
+	EvalArgs                         []interface{}
+	EvalReturnCmd                    *redis.Cmd
+	HSetKey                          string
+	HSetField                        string
+	HSetValue                        interface{}
+	HSetReturnBoolCmd                *redis.BoolCmd
+	HGetAllKey                       string
+	HGetAllReturnStringStringMapCmd  *redis.StringStringMapCmd
+}
+
+//XAdd records the input params and returns specified results
+func (t *TestRedisClient) XAdd(a *redis.XAddArgs) *redis.StringCmd {
+	t.XAddArgs = a
+	return t.XAddReturnStringCmd
+}
+
+//XRead records the input params and returns specified results
+func (t *TestRedisClient) XRead(a *redis.XReadArgs) *redis.XStreamSliceCmd {
+	t.XReadArgs = a
+	return t.XReadReturnXStreamSliceCmd
+}
+
+//TxPipeline returns specified results
+func (t *TestRedisClient) TxPipeline() redis.Pipeliner {
+	return t.TxPipelineReturnPipeliner
+}
+
+//Pipeline returns specified results
+func (t *TestRedisClient) Pipeline() redis.Pipeliner {
+	return t.PipelineReturnPipeliner
+}
+
+//Sort records the input params and returns specified results
+func (t *TestRedisClient) Sort(set string, sort *redis.Sort) *redis.StringSliceCmd {
+	t.SortSet, t.SortSort = set, sort
+	return t.SortReturnStringSliceCmd
+}
+
+//Watch records the input params and returns specified results
+func (t *TestRedisClient) Watch(fn func(*redis.Tx) error, keys ...string) error {
+	t.WatchKeys = keys
+	return t.WatchReturnError
+}
+
+//XReadGroup records the input params and returns specified results
+func (t *TestRedisClient) XReadGroup(a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	t.XReadGroupArgs = a
+	return t.XReadGroupReturnXStreamSliceCmd
+}
+
+//XAck records the input params and returns specified results
+func (t *TestRedisClient) XAck(stream, group string, ids ...string) *redis.IntCmd {
+	t.XAckStream, t.XAckGroup, t.XAckIDs = stream, group, ids
+	return t.XAckReturnIntCmd
+}
+
+//XPendingExt records the input params and returns specified results
+func (t *TestRedisClient) XPendingExt(a *redis.XPendingExtArgs) *redis.XPendingExtCmd {
+	t.XPendingExtArgs = a
+	return t.XPendingExtReturnXPendingExtCmd
+}
+
+//XClaim records the input params and returns specified results
+func (t *TestRedisClient) XClaim(a *redis.XClaimArgs) *redis.XMessageSliceCmd {
+	t.XClaimArgs = a
+	return t.XClaimReturnXMessageSliceCmd
+}
+
+//Do records the input params and returns specified results
+func (t *TestRedisClient) Do(args ...interface{}) *redis.Cmd {
+	t.DoArgs = args
+	return t.DoReturnCmd
+}
+
+//Set records the input params and returns specified results
+func (t *TestRedisClient) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	t.SetKey, t.SetValue, t.SetExpiration = key, value, expiration
+	return t.SetReturnStatusCmd
+}
+
+//Exists records the input params and returns specified results
+func (t *TestRedisClient) Exists(keys ...string) *redis.IntCmd {
+	t.ExistsKeys = keys
+	return t.ExistsReturnIntCmd
+}
+
+//XRangeN records the input params and returns specified results
+func (t *TestRedisClient) XRangeN(stream, start, stop string, count int64) *redis.XMessageSliceCmd {
+	t.XRangeNStream, t.XRangeNStart, t.XRangeNStop, t.XRangeNCount = stream, start, stop, count
+	return t.XRangeNReturnXMessageSliceCmd
+}
+
+//XRevRangeN records the input params and returns specified results
+func (t *TestRedisClient) XRevRangeN(stream, start, stop string, count int64) *redis.XMessageSliceCmd {
+	t.XRevRangeNStream, t.XRevRangeNStart, t.XRevRangeNStop, t.XRevRangeNCount = stream, start, stop, count
+	return t.XRevRangeNReturnXMessageSliceCmd
+}
+
+//Del records the input params and returns specified results
+func (t *TestRedisClient) Del(keys ...string) *redis.IntCmd {
+	t.DelKeys = keys
+	return t.DelReturnIntCmd
+}
+
+//Eval records the input params and returns specified results
+func (t *TestRedisClient) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	t.EvalScript, t.EvalKeys, t.EvalArgs = script, keys, args
+	return t.EvalReturnCmd
+}
+
+//HSet records the input params and returns specified results
+func (t *TestRedisClient) HSet(key, field string, value interface{}) *redis.BoolCmd {
+	t.HSetKey, t.HSetField, t.HSetValue = key, field, value
+	return t.HSetReturnBoolCmd
+}
+
+//HGetAll records the input params and returns specified results
+func (t *TestRedisClient) HGetAll(key string) *redis.StringStringMapCmd {
+	t.HGetAllKey = key
+	return t.HGetAllReturnStringStringMapCmd
+}
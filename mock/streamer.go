@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"context"
 	"time"
 
 	"github.com/antekresic/grs/domain"
@@ -18,46 +19,328 @@ func (r TestClock) Now() time.Time {
 
 //TestRepo is a mock of the domain.EntryRepository used for testing purposes
 type TestRepo struct {
-	AddEntryEntry           domain.Entry
-	AddEntryReturnError     error
-	GetEntriesLastID        string
-	GetEntriesReturnEntries []domain.Entry
-	GetEntriesReturnLastID  string
-	GetEntriesReturnError   error
-	StoreCursorCursor       domain.StreamCursor
-	StoreCursorReturnError  error
-	GetCursorsReturnCursors []domain.StreamCursor
-	GetCursorsReturnError   error
-	StealCursorOldCursor    domain.StreamCursor
-	StealCursorNewName      string
-	StealCursorReturnError  error
+	AddEntryEntry             domain.Entry
+	AddEntryReturnID          string
+	AddEntryReturnError       error
+	AddEntryOnceEntry         domain.Entry
+	AddEntryOnceReturnID      string
+	AddEntryOnceReturnCreated bool
+	AddEntryOnceReturnError   error
+	GetEntryID                string
+	GetEntryReturnEntry       domain.Entry
+	GetEntryReturnError       error
+	QueryEntriesQuery         domain.EntryQuery
+	QueryEntriesReturnPage    domain.EntryPage
+	QueryEntriesReturnError   error
+	TailEntriesQuery          domain.EntryQuery
+	TailEntriesReturnPage     domain.EntryPage
+	TailEntriesReturnError    error
+	AddEntriesEntries         []domain.Entry
+	AddEntriesReturnResults   []domain.AddResult
+	AddEntriesReturnError     error
+	GetEntriesPositions       map[string]string
+	GetEntriesReturnEntries   []domain.Entry
+	GetEntriesReturnPositions map[string]string
+	GetEntriesReturnError     error
+	StoreCursorCursor         domain.StreamCursor
+	StoreCursorReturnError    error
+	GetCursorsReturnCursors   []domain.StreamCursor
+	GetCursorsReturnError     error
+	StealCursorOldCursor      domain.StreamCursor
+	StealCursorNewName        string
+	StealCursorReturnError    error
+	ReleaseCursorName         string
+	ReleaseCursorReturnError  error
+	RefreshCursorCursor       domain.StreamCursor
+	RefreshCursorReturnError  error
+	HoldEntriesStream         string
+	HoldEntriesName           string
+	HoldEntriesID             string
+	HoldEntriesReturnError    error
+	CountAttemptName          string
+	CountAttemptStream        string
+	CountAttemptID            string
+	CountAttemptReturnCount   int
+	CountAttemptReturnError   error
 }
 
 //AddEntry records the input params and returns specified results
-func (t *TestRepo) AddEntry(e domain.Entry) error {
+func (t *TestRepo) AddEntry(ctx context.Context, e domain.Entry) (string, error) {
 	t.AddEntryEntry = e
-	return t.AddEntryReturnError
+	return t.AddEntryReturnID, t.AddEntryReturnError
+}
+
+//AddEntryOnce records the input params and returns specified results
+func (t *TestRepo) AddEntryOnce(ctx context.Context, e domain.Entry) (string, bool, error) {
+	t.AddEntryOnceEntry = e
+	return t.AddEntryOnceReturnID, t.AddEntryOnceReturnCreated, t.AddEntryOnceReturnError
+}
+
+//GetEntry records the input params and returns specified results
+func (t *TestRepo) GetEntry(ctx context.Context, ID string) (domain.Entry, error) {
+	t.GetEntryID = ID
+	return t.GetEntryReturnEntry, t.GetEntryReturnError
+}
+
+//AddEntries records the input params and returns specified results
+func (t *TestRepo) AddEntries(ctx context.Context, entries []domain.Entry) ([]domain.AddResult, error) {
+	t.AddEntriesEntries = entries
+	return t.AddEntriesReturnResults, t.AddEntriesReturnError
+}
+
+//QueryEntries records the input params and returns specified results
+func (t *TestRepo) QueryEntries(ctx context.Context, q domain.EntryQuery) (domain.EntryPage, error) {
+	t.QueryEntriesQuery = q
+	return t.QueryEntriesReturnPage, t.QueryEntriesReturnError
+}
+
+//CountAttempt records the input params and returns specified results
+func (t *TestRepo) CountAttempt(ctx context.Context, name, stream, ID string) (int, error) {
+	t.CountAttemptName, t.CountAttemptStream, t.CountAttemptID = name, stream, ID
+	return t.CountAttemptReturnCount, t.CountAttemptReturnError
+}
+
+//HoldEntries records the input params and returns specified results
+func (t *TestRepo) HoldEntries(ctx context.Context, stream, name, ID string) error {
+	t.HoldEntriesStream, t.HoldEntriesName, t.HoldEntriesID = stream, name, ID
+	return t.HoldEntriesReturnError
+}
+
+//TailEntries records the input params and returns specified results
+func (t *TestRepo) TailEntries(ctx context.Context, q domain.EntryQuery) (domain.EntryPage, error) {
+	t.TailEntriesQuery = q
+	return t.TailEntriesReturnPage, t.TailEntriesReturnError
 }
 
 //GetEntries records the input params and returns specified results
-func (t *TestRepo) GetEntries(lastID string) (entries []domain.Entry, newLastID string, err error) {
-	t.GetEntriesLastID = lastID
-	return t.GetEntriesReturnEntries, t.GetEntriesReturnLastID, t.GetEntriesReturnError
+func (t *TestRepo) GetEntries(ctx context.Context, positions map[string]string) (entries []domain.Entry, newPositions map[string]string, err error) {
+	t.GetEntriesPositions = positions
+	return t.GetEntriesReturnEntries, t.GetEntriesReturnPositions, t.GetEntriesReturnError
 }
 
 //StoreCursor records the input params and returns specified results
-func (t *TestRepo) StoreCursor(c domain.StreamCursor) error {
+func (t *TestRepo) StoreCursor(ctx context.Context, c domain.StreamCursor) error {
 	t.StoreCursorCursor = c
 	return t.StoreCursorReturnError
 }
 
 //GetCursors records the input params and returns specified results
-func (t *TestRepo) GetCursors() (cursors []domain.StreamCursor, err error) {
+func (t *TestRepo) GetCursors(ctx context.Context) (cursors []domain.StreamCursor, err error) {
 	return t.GetCursorsReturnCursors, t.GetCursorsReturnError
 }
 
 //StealCursor records the input params and returns specified results
-func (t *TestRepo) StealCursor(oldCursor domain.StreamCursor, newName string) error {
+func (t *TestRepo) StealCursor(ctx context.Context, oldCursor domain.StreamCursor, newName string) error {
 	t.StealCursorOldCursor, t.StealCursorNewName = oldCursor, newName
 	return t.StealCursorReturnError
 }
+
+//TestGroupRepo is a mock of the domain.GroupRepository used for testing purposes
+type TestGroupRepo struct {
+	CreateGroupGroup             string
+	CreateGroupReturnError       error
+	GetGroupEntriesGroup         string
+	GetGroupEntriesConsumer      string
+	GetGroupEntriesLastID        string
+	GetGroupEntriesReturnEntries []domain.Entry
+	GetGroupEntriesReturnError   error
+	AckEntryGroup                string
+	AckEntryID                   string
+	AckEntryReturnError          error
+	GetPendingEntriesGroup       string
+	GetPendingEntriesStarts      []string
+	GetPendingEntriesCount       int64
+	GetPendingEntriesReturnPages map[string][]domain.PendingEntry
+	GetPendingEntriesReturnError error
+	ClaimEntriesGroup            string
+	ClaimEntriesConsumer         string
+	ClaimEntriesMinIdle          time.Duration
+	ClaimEntriesIDs              []string
+	ClaimEntriesReturnEntries    []domain.Entry
+	ClaimEntriesReturnError      error
+	StoreHeartConsumer           string
+	StoreHeartTimeout            time.Duration
+	StoreHeartReturnError        error
+	HasHeartConsumers            []string
+	HasHeartReturnHearts         map[string]bool
+	HasHeartReturnError          error
+	RemoveHeartConsumer          string
+	RemoveHeartReturnError       error
+}
+
+//CreateGroup records the input params and returns specified results
+func (t *TestGroupRepo) CreateGroup(ctx context.Context, group string) error {
+	t.CreateGroupGroup = group
+	return t.CreateGroupReturnError
+}
+
+//GetGroupEntries records the input params and returns specified results
+func (t *TestGroupRepo) GetGroupEntries(ctx context.Context, group, consumer, lastID string) ([]domain.Entry, error) {
+	t.GetGroupEntriesGroup, t.GetGroupEntriesConsumer, t.GetGroupEntriesLastID = group, consumer, lastID
+	return t.GetGroupEntriesReturnEntries, t.GetGroupEntriesReturnError
+}
+
+//AckEntry records the input params and returns specified results
+func (t *TestGroupRepo) AckEntry(ctx context.Context, group, ID string) error {
+	t.AckEntryGroup, t.AckEntryID = group, ID
+	return t.AckEntryReturnError
+}
+
+//GetPendingEntries records the input params and returns the page specified for the start
+func (t *TestGroupRepo) GetPendingEntries(ctx context.Context, group, start string, count int64) ([]domain.PendingEntry, error) {
+	t.GetPendingEntriesGroup, t.GetPendingEntriesCount = group, count
+	t.GetPendingEntriesStarts = append(t.GetPendingEntriesStarts, start)
+	return t.GetPendingEntriesReturnPages[start], t.GetPendingEntriesReturnError
+}
+
+//ClaimEntries records the input params and returns specified results
+func (t *TestGroupRepo) ClaimEntries(ctx context.Context, group, consumer string, minIdle time.Duration, IDs []string) ([]domain.Entry, error) {
+	t.ClaimEntriesGroup, t.ClaimEntriesConsumer = group, consumer
+	t.ClaimEntriesMinIdle, t.ClaimEntriesIDs = minIdle, IDs
+	return t.ClaimEntriesReturnEntries, t.ClaimEntriesReturnError
+}
+
+//StoreHeart records the input params and returns specified results
+func (t *TestGroupRepo) StoreHeart(ctx context.Context, consumer string, timeout time.Duration) error {
+	t.StoreHeartConsumer, t.StoreHeartTimeout = consumer, timeout
+	return t.StoreHeartReturnError
+}
+
+//HasHeart records the input params and returns the heart specified for the consumer
+func (t *TestGroupRepo) HasHeart(ctx context.Context, consumer string) (bool, error) {
+	t.HasHeartConsumers = append(t.HasHeartConsumers, consumer)
+	return t.HasHeartReturnHearts[consumer], t.HasHeartReturnError
+}
+
+//ReleaseCursor records the input params and returns specified results
+func (t *TestRepo) ReleaseCursor(ctx context.Context, name string) error {
+	t.ReleaseCursorName = name
+	return t.ReleaseCursorReturnError
+}
+
+//RefreshCursor records the input params and returns specified results
+func (t *TestRepo) RefreshCursor(ctx context.Context, c domain.StreamCursor) error {
+	t.RefreshCursorCursor = c
+	return t.RefreshCursorReturnError
+}
+
+//RemoveHeart records the input params and returns specified results
+func (t *TestGroupRepo) RemoveHeart(ctx context.Context, consumer string) error {
+	t.RemoveHeartConsumer = consumer
+	return t.RemoveHeartReturnError
+}
+
+//TestRetentionRepo is a mock of the domain.RetentionRepository used for testing purposes
+type TestRetentionRepo struct {
+	GetCursorsCalled         bool
+	GetCursorsReturnCursors  []domain.StreamCursor
+	GetCursorsReturnError    error
+	TrimEntriesMaxIDs        map[string]string
+	TrimEntriesReturnTrimmed map[string]int64
+	TrimEntriesReturnError   error
+	GetHoldsReturnHolds      map[string]map[string]string
+	GetHoldsReturnError      error
+}
+
+//GetCursors records the call and returns specified results
+func (t *TestRetentionRepo) GetCursors(ctx context.Context) ([]domain.StreamCursor, error) {
+	t.GetCursorsCalled = true
+	return t.GetCursorsReturnCursors, t.GetCursorsReturnError
+}
+
+//TrimEntries records the input params by stream and returns the count specified for the stream
+func (t *TestRetentionRepo) TrimEntries(ctx context.Context, stream, maxID string) (int64, error) {
+	if t.TrimEntriesMaxIDs == nil {
+		t.TrimEntriesMaxIDs = make(map[string]string)
+	}
+
+	t.TrimEntriesMaxIDs[stream] = maxID
+	return t.TrimEntriesReturnTrimmed[stream], t.TrimEntriesReturnError
+}
+
+//GetHolds returns the holds specified for the stream
+func (t *TestRetentionRepo) GetHolds(ctx context.Context, stream string) (map[string]string, error) {
+	return t.GetHoldsReturnHolds[stream], t.GetHoldsReturnError
+}
+
+//TestMonitorRepo is a mock of the domain.MonitorRepository used for testing purposes
+type TestMonitorRepo struct {
+	GetCursorsReturnCursors      []domain.StreamCursor
+	GetCursorsReturnError        error
+	GetStreamInfoReturnInfos     map[string]domain.StreamInfo
+	GetStreamInfoReturnError     error
+	GetDeadLetterInfoReturnInfo  domain.StreamInfo
+	GetDeadLetterInfoReturnError error
+}
+
+//GetCursors returns specified results
+func (t *TestMonitorRepo) GetCursors(ctx context.Context) ([]domain.StreamCursor, error) {
+	return t.GetCursorsReturnCursors, t.GetCursorsReturnError
+}
+
+//GetStreamInfo returns the info specified for the stream
+func (t *TestMonitorRepo) GetStreamInfo(ctx context.Context, stream string) (domain.StreamInfo, error) {
+	return t.GetStreamInfoReturnInfos[stream], t.GetStreamInfoReturnError
+}
+
+//GetDeadLetterInfo returns specified results
+func (t *TestMonitorRepo) GetDeadLetterInfo(ctx context.Context) (domain.StreamInfo, error) {
+	return t.GetDeadLetterInfoReturnInfo, t.GetDeadLetterInfoReturnError
+}
+
+//TestHealthRepo is a mock of the domain.HealthRepository used for testing purposes
+type TestHealthRepo struct {
+	GetStreamHealthReturnHealth []domain.StreamHealth
+	GetStreamHealthReturnError  error
+}
+
+//GetStreamHealth returns specified results
+func (t *TestHealthRepo) GetStreamHealth(ctx context.Context) ([]domain.StreamHealth, error) {
+	return t.GetStreamHealthReturnHealth, t.GetStreamHealthReturnError
+}
+
+//TestDeadLetterRepo is a mock of the domain.DeadLetterRepository used for testing purposes
+type TestDeadLetterRepo struct {
+	DeadLetterEntry             domain.Entry
+	DeadLetterReturnError       error
+	GetDeadEntriesStart         string
+	GetDeadEntriesReturnEntries []domain.DeadEntry
+	GetDeadEntriesReturnError   error
+	GetDeadEntryID              string
+	GetDeadEntryReturnEntry     domain.DeadEntry
+	GetDeadEntryReturnError     error
+	ReplayDeadEntryID           string
+	ReplayDeadEntryReturnError  error
+	PurgeDeadEntriesIDs         []string
+	PurgeDeadEntriesReturnError error
+}
+
+//DeadLetter records the input params and returns specified results
+func (t *TestDeadLetterRepo) DeadLetter(ctx context.Context, e domain.Entry, reason string, attempts []domain.Attempt) error {
+	t.DeadLetterEntry = e
+	return t.DeadLetterReturnError
+}
+
+//GetDeadEntries records the input params and returns specified results
+func (t *TestDeadLetterRepo) GetDeadEntries(ctx context.Context, start string, count int64) ([]domain.DeadEntry, error) {
+	t.GetDeadEntriesStart = start
+	return t.GetDeadEntriesReturnEntries, t.GetDeadEntriesReturnError
+}
+
+//GetDeadEntry records the input params and returns specified results
+func (t *TestDeadLetterRepo) GetDeadEntry(ctx context.Context, ID string) (domain.DeadEntry, error) {
+	t.GetDeadEntryID = ID
+	return t.GetDeadEntryReturnEntry, t.GetDeadEntryReturnError
+}
+
+//ReplayDeadEntry records the input params and returns specified results
+func (t *TestDeadLetterRepo) ReplayDeadEntry(ctx context.Context, ID string) error {
+	t.ReplayDeadEntryID = ID
+	return t.ReplayDeadEntryReturnError
+}
+
+//PurgeDeadEntries records the input params and returns specified results
+func (t *TestDeadLetterRepo) PurgeDeadEntries(ctx context.Context, IDs ...string) error {
+	t.PurgeDeadEntriesIDs = IDs
+	return t.PurgeDeadEntriesReturnError
+}
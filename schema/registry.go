@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	schemaSuffix  string = ".json"
+	versionPrefix string = "v"
+
+	//DefaultReloadInterval is how often the schema directory is checked for changes
+	DefaultReloadInterval time.Duration = 10 * time.Second
+)
+
+//Key identifies the schema of the meta of entries with the object type, action and schema version.
+type Key struct {
+	ObjectType int
+	Action     string
+	Version    int
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("object type %d, action %s, version %d", k.ObjectType, k.Action, k.Version)
+}
+
+//Registry holds the schemas loaded from the files of a directory.
+//Files are named {object_type}.{action}.v{version}.json, like 2.create.v1.json,
+//other files in the directory are ignored.
+type Registry struct {
+	Dir string
+
+	mu          sync.RWMutex
+	schemas     map[Key]*Schema
+	fingerprint string
+}
+
+//Load reads all the schemas from the directory, replacing the ones loaded before.
+//If any schema is invalid nothing is replaced.
+func (r *Registry) Load() error {
+	_, err := r.load(true)
+	return err
+}
+
+//Reload loads the schemas again if any of the files changed since the last load.
+//Reports whether the schemas were reloaded.
+func (r *Registry) Reload() (bool, error) {
+	return r.load(false)
+}
+
+//Watch reloads the schemas when the files change, checking every interval until the context is done.
+//Failed reloads are logged and keep the schemas loaded before.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := r.Reload()
+
+		if err != nil {
+			log.Printf("Error reloading schemas from %s: %s\n", r.Dir, err)
+			continue
+		}
+
+		if reloaded {
+			log.Printf("Reloaded %d schemas from %s\n", r.Len(), r.Dir)
+		}
+	}
+}
+
+//Lookup returns the schema registered under the key.
+func (r *Registry) Lookup(k Key) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schemas[k]
+
+	return s, ok
+}
+
+//Len returns the number of schemas loaded.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.schemas)
+}
+
+func (r *Registry) load(force bool) (bool, error) {
+	files, err := ioutil.ReadDir(r.Dir)
+
+	if err != nil {
+		return false, fmt.Errorf("load: %s", err)
+	}
+
+	var names, fingerprint []string
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), schemaSuffix) {
+			continue
+		}
+
+		names = append(names, f.Name())
+		fingerprint = append(fingerprint, fmt.Sprintf("%s:%d:%d", f.Name(), f.Size(), f.ModTime().UnixNano()))
+	}
+
+	sort.Strings(fingerprint)
+	current := strings.Join(fingerprint, ",")
+
+	r.mu.RLock()
+	unchanged := r.schemas != nil && current == r.fingerprint
+	r.mu.RUnlock()
+
+	if unchanged && !force {
+		return false, nil
+	}
+
+	schemas := make(map[Key]*Schema, len(names))
+
+	for _, name := range names {
+		key, err := parseName(name)
+
+		if err != nil {
+			return false, fmt.Errorf("load: %s: %s", name, err)
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(r.Dir, name))
+
+		if err != nil {
+			return false, fmt.Errorf("load: %s", err)
+		}
+
+		schemas[key], err = Parse(content)
+
+		if err != nil {
+			return false, fmt.Errorf("load: %s: %s", name, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.schemas = schemas
+	r.fingerprint = current
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+//parseName reads the key of the schema from the name of its file.
+func parseName(name string) (Key, error) {
+	parts := strings.Split(strings.TrimSuffix(name, schemaSuffix), ".")
+
+	if len(parts) != 3 || parts[1] == "" || !strings.HasPrefix(parts[2], versionPrefix) {
+		return Key{}, fmt.Errorf("name must be {object_type}.{action}.v{version}%s", schemaSuffix)
+	}
+
+	objectType, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid object type %s", parts[0])
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[2], versionPrefix))
+
+	if err != nil || version < 1 {
+		return Key{}, fmt.Errorf("invalid version %s", parts[2])
+	}
+
+	return Key{ObjectType: objectType, Action: parts[1], Version: version}, nil
+}
@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSchema(t *testing.T, dir, name, content string) {
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), "Error writing schema")
+}
+
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "schema")
+	require.Nil(t, err, "Error creating directory")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Load schemas", func(t *testing.T) {
+		dir := testDir(t)
+		writeSchema(t, dir, "2.create.v1.json", `{"type": "object"}`)
+		writeSchema(t, dir, "2.create.v2.json", `{"type": "object", "required": ["name"]}`)
+		writeSchema(t, dir, "README.md", `not a schema`)
+
+		r := &Registry{Dir: dir}
+
+		assert.Nil(t, r.Load(), "Error is not nil")
+		assert.Equal(t, 2, r.Len(), "Schemas not loaded")
+
+		_, ok := r.Lookup(Key{ObjectType: 2, Action: "create", Version: 2})
+		assert.True(t, ok, "Schema not found")
+
+		_, ok = r.Lookup(Key{ObjectType: 2, Action: "update", Version: 1})
+		assert.False(t, ok, "Schema found")
+	})
+
+	t.Run("Invalid file name", func(t *testing.T) {
+		dir := testDir(t)
+		writeSchema(t, dir, "2.create.json", `{}`)
+
+		r := &Registry{Dir: dir}
+
+		assert.NotNil(t, r.Load(), "Error is nil")
+	})
+
+	t.Run("Reload only when files change", func(t *testing.T) {
+		dir := testDir(t)
+		writeSchema(t, dir, "2.create.v1.json", `{}`)
+
+		r := &Registry{Dir: dir}
+		require.Nil(t, r.Load(), "Error loading schemas")
+
+		reloaded, err := r.Reload()
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.False(t, reloaded, "Reloaded without changes")
+
+		writeSchema(t, dir, "3.delete.v1.json", `{}`)
+
+		reloaded, err = r.Reload()
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.True(t, reloaded, "Not reloaded after a change")
+		assert.Equal(t, 2, r.Len(), "Schemas not reloaded")
+	})
+
+	t.Run("Failed reload keeps the schemas", func(t *testing.T) {
+		dir := testDir(t)
+		writeSchema(t, dir, "2.create.v1.json", `{}`)
+
+		r := &Registry{Dir: dir}
+		require.Nil(t, r.Load(), "Error loading schemas")
+
+		writeSchema(t, dir, "3.delete.v1.json", `{"oneOf": []}`)
+
+		_, err := r.Reload()
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Equal(t, 1, r.Len(), "Schemas replaced")
+	})
+}
+
+func TestValidator(t *testing.T) {
+	dir := testDir(t)
+	writeSchema(t, dir, "2.create.v1.json", `{"type": "object", "required": ["name"]}`)
+
+	r := &Registry{Dir: dir}
+	require.Nil(t, r.Load(), "Error loading schemas")
+
+	v := Validator{Registry: r}
+	ctx := context.Background()
+
+	entry := func(version int, meta string) domain.Entry {
+		return domain.Entry{ObjectID: 1, ObjectType: 2, Action: "create", SchemaVersion: version, Meta: json.RawMessage(meta)}
+	}
+
+	t.Run("Matching entry", func(t *testing.T) {
+		assert.Nil(t, v.Validate(ctx, entry(1, `{"name": "my object"}`)), "Error is not nil")
+	})
+
+	t.Run("Unversioned entry", func(t *testing.T) {
+		assert.Nil(t, v.Validate(ctx, entry(0, `"JSON"`)), "Error is not nil")
+	})
+
+	t.Run("Entry not matching the schema", func(t *testing.T) {
+		err := v.Validate(ctx, entry(1, `{}`))
+
+		assert.Equal(
+			t,
+			&domain.ValidationError{Errors: []domain.FieldError{{Path: "/meta/name", Message: "is required"}}},
+			err,
+			"Error not correct",
+		)
+	})
+
+	t.Run("Missing meta", func(t *testing.T) {
+		err := v.Validate(ctx, entry(1, ``))
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Version without a schema", func(t *testing.T) {
+		err := v.Validate(ctx, entry(2, `{"name": "my object"}`))
+
+		assert.EqualError(
+			t,
+			err,
+			"invalid entry: /schema_version has no schema registered for object type 2, action create, version 2",
+			"Error not correct",
+		)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	dir := testDir(t)
+
+	r := &Registry{Dir: dir}
+	require.Nil(t, r.Load(), "Error loading schemas")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go r.Watch(ctx, time.Millisecond)
+
+	writeSchema(t, dir, "2.create.v1.json", `{}`)
+
+	deadline := time.Now().Add(time.Second)
+
+	for r.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, 1, r.Len(), "Schemas not reloaded")
+}
@@ -0,0 +1,404 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//annotations are keywords which do not take part in validation and are ignored.
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"format":      true,
+}
+
+var types = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+//Schema is a compiled JSON Schema. Only a subset of the validation keywords is supported:
+//type, enum, const, properties, required, additionalProperties, items, minItems, maxItems,
+//minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength and pattern.
+//Schemas using any other keyword are rejected when parsed, so no rule is silently ignored.
+type Schema struct {
+	//reject is set for the false schema, which no value matches
+	reject bool
+
+	types      []string
+	enum       []interface{}
+	properties map[string]*Schema
+	required   []string
+	additional *Schema
+	items      *Schema
+
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+}
+
+//Violation is a single rule of the schema which the value breaks.
+//Path is the JSON pointer to the offending value.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+//Parse compiles a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var doc interface{}
+	err := json.Unmarshal(data, &doc)
+
+	if err != nil {
+		return nil, fmt.Errorf("Parse: %s", err)
+	}
+
+	s, err := compile(doc, "schema")
+
+	if err != nil {
+		return nil, fmt.Errorf("Parse: %s", err)
+	}
+
+	return s, nil
+}
+
+func compile(doc interface{}, path string) (*Schema, error) {
+	switch d := doc.(type) {
+	case bool:
+		return &Schema{reject: !d}, nil
+	case map[string]interface{}:
+		s := &Schema{}
+
+		for _, keyword := range sortedKeys(d) {
+			if annotations[keyword] {
+				continue
+			}
+
+			err := s.compileKeyword(keyword, d[keyword], path+"/"+keyword)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: must be an object or a boolean", path)
+	}
+}
+
+func (s *Schema) compileKeyword(keyword string, value interface{}, path string) error {
+	var err error
+
+	switch keyword {
+	case "type":
+		s.types, err = compileTypes(value)
+	case "enum":
+		values, ok := value.([]interface{})
+
+		if !ok {
+			err = fmt.Errorf("must be an array")
+		}
+
+		s.enum = values
+	case "const":
+		s.enum = []interface{}{value}
+	case "properties":
+		properties, ok := value.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+
+		s.properties = make(map[string]*Schema, len(properties))
+
+		for name, property := range properties {
+			s.properties[name], err = compile(property, path+"/"+name)
+
+			if err != nil {
+				return err
+			}
+		}
+	case "required":
+		s.required, err = compileStrings(value)
+	case "additionalProperties":
+		s.additional, err = compile(value, path)
+		return err
+	case "items":
+		s.items, err = compile(value, path)
+		return err
+	case "minItems":
+		s.minItems, err = compileCount(value)
+	case "maxItems":
+		s.maxItems, err = compileCount(value)
+	case "minLength":
+		s.minLength, err = compileCount(value)
+	case "maxLength":
+		s.maxLength, err = compileCount(value)
+	case "pattern":
+		pattern, ok := value.(string)
+
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+
+		s.pattern, err = regexp.Compile(pattern)
+	case "minimum":
+		s.minimum, err = compileNumber(value)
+	case "maximum":
+		s.maximum, err = compileNumber(value)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = compileNumber(value)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = compileNumber(value)
+	default:
+		return fmt.Errorf("%s: unsupported keyword", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	return nil
+}
+
+func compileTypes(value interface{}) ([]string, error) {
+	if t, ok := value.(string); ok {
+		value = []interface{}{t}
+	}
+
+	names, err := compileStrings(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if !types[name] {
+			return nil, fmt.Errorf("unknown type %s", name)
+		}
+	}
+
+	return names, nil
+}
+
+func compileStrings(value interface{}) ([]string, error) {
+	values, ok := value.([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+
+	strs := make([]string, len(values))
+
+	for i, v := range values {
+		if strs[i], ok = v.(string); !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+	}
+
+	return strs, nil
+}
+
+func compileCount(value interface{}) (*int, error) {
+	n, ok := value.(float64)
+
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+
+	count := int(n)
+
+	return &count, nil
+}
+
+func compileNumber(value interface{}) (*float64, error) {
+	n, ok := value.(float64)
+
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+
+	return &n, nil
+}
+
+//Validate checks a value decoded by encoding/json against the schema.
+//Path is the JSON pointer of the value, prefixed to the paths of the violations.
+func (s *Schema) Validate(value interface{}, path string) []Violation {
+	if s.reject {
+		return []Violation{{Path: path, Message: "is not allowed"}}
+	}
+
+	if len(s.types) > 0 && !s.hasType(value) {
+		return []Violation{{Path: path, Message: "must be of type " + strings.Join(s.types, " or ")}}
+	}
+
+	var violations []Violation
+
+	add := func(format string, args ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.enum != nil && !s.inEnum(value) {
+		add("must be one of %s", formatValues(s.enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		violations = append(violations, s.validateObject(v, path)...)
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			add("must have at least %d items", *s.minItems)
+		}
+
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			add("must have at most %d items", *s.maxItems)
+		}
+
+		if s.items != nil {
+			for i, item := range v {
+				violations = append(violations, s.items.Validate(item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+
+		if s.minLength != nil && length < *s.minLength {
+			add("must be at least %d characters long", *s.minLength)
+		}
+
+		if s.maxLength != nil && length > *s.maxLength {
+			add("must be at most %d characters long", *s.maxLength)
+		}
+
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			add("must match the pattern %s", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			add("must be greater than or equal to %g", *s.minimum)
+		}
+
+		if s.maximum != nil && v > *s.maximum {
+			add("must be less than or equal to %g", *s.maximum)
+		}
+
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			add("must be greater than %g", *s.exclusiveMinimum)
+		}
+
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			add("must be less than %g", *s.exclusiveMaximum)
+		}
+	}
+
+	return violations
+}
+
+func (s *Schema) validateObject(object map[string]interface{}, path string) []Violation {
+	var violations []Violation
+
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			violations = append(violations, Violation{Path: path + "/" + escape(name), Message: "is required"})
+		}
+	}
+
+	for _, name := range sortedKeys(object) {
+		property, ok := s.properties[name]
+
+		if !ok {
+			property = s.additional
+		}
+
+		if property != nil {
+			violations = append(violations, property.Validate(object[name], path+"/"+escape(name))...)
+		}
+	}
+
+	return violations
+}
+
+func (s *Schema) hasType(value interface{}) bool {
+	for _, t := range s.types {
+		if typeOf(value) == t {
+			return true
+		}
+
+		//Integers are numbers without a fraction, they match the number type too.
+		if n, ok := value.(float64); ok && t == "integer" && n == math.Trunc(n) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Schema) inEnum(value interface{}) bool {
+	for _, v := range s.enum {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func formatValues(values []interface{}) string {
+	//Values decoded from JSON always encode.
+	content, _ := json.Marshal(values)
+	return string(content)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+//escape escapes a property name for use in a JSON pointer.
+func escape(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
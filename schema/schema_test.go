@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"title": "User",
+	"type": "object",
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 5, "pattern": "^[a-z]+$"},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"address": {"type": ["object", "null"], "properties": {"city": {"const": "Zagreb"}}}
+	}
+}`
+
+func validate(t *testing.T, s *Schema, document string) []Violation {
+	var value interface{}
+	require.Nil(t, json.Unmarshal([]byte(document), &value), "Error decoding document")
+
+	return s.Validate(value, "/meta")
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	require.Nil(t, err, "Error parsing schema")
+
+	t.Run("Valid document", func(t *testing.T) {
+		violations := validate(t, s, `{"name": "ana", "age": 30, "role": "admin", "tags": ["a"], "address": null}`)
+
+		assert.Empty(t, violations, "Violations not empty")
+	})
+
+	t.Run("Wrong type", func(t *testing.T) {
+		violations := validate(t, s, `[]`)
+
+		assert.Equal(t, []Violation{{Path: "/meta", Message: "must be of type object"}}, violations, "Violations not correct")
+	})
+
+	t.Run("Missing and additional properties", func(t *testing.T) {
+		violations := validate(t, s, `{"name": "ana", "nickname": "a"}`)
+
+		assert.Equal(
+			t,
+			[]Violation{
+				{Path: "/meta/age", Message: "is required"},
+				{Path: "/meta/nickname", Message: "is not allowed"},
+			},
+			violations,
+			"Violations not correct",
+		)
+	})
+
+	t.Run("Broken rules", func(t *testing.T) {
+		violations := validate(
+			t,
+			s,
+			`{"name": "Ana Maria", "age": 150.5, "role": "root", "tags": ["a", 1, "c"], "address": {"city": "Split"}}`,
+		)
+
+		assert.Equal(
+			t,
+			[]Violation{
+				{Path: "/meta/address/city", Message: `must be one of ["Zagreb"]`},
+				{Path: "/meta/age", Message: "must be of type integer"},
+				{Path: "/meta/name", Message: "must be at most 5 characters long"},
+				{Path: "/meta/name", Message: "must match the pattern ^[a-z]+$"},
+				{Path: "/meta/role", Message: `must be one of ["admin","user"]`},
+				{Path: "/meta/tags", Message: "must have at most 2 items"},
+				{Path: "/meta/tags/1", Message: "must be of type string"},
+			},
+			violations,
+			"Violations not correct",
+		)
+	})
+
+	t.Run("Number limits", func(t *testing.T) {
+		violations := validate(t, s, `{"name": "ana", "age": 150}`)
+
+		assert.Equal(t, []Violation{{Path: "/meta/age", Message: "must be less than 150"}}, violations, "Violations not correct")
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Unsupported keyword", func(t *testing.T) {
+		_, err := Parse([]byte(`{"properties": {"name": {"oneOf": [{"type": "string"}]}}}`))
+
+		assert.EqualError(t, err, "Parse: schema/properties/name/oneOf: unsupported keyword", "Error not correct")
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		_, err := Parse([]byte(`{"type": "text"}`))
+
+		assert.EqualError(t, err, "Parse: schema/type: unknown type text", "Error not correct")
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := Parse([]byte(`{"pattern": "("}`))
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Boolean schema", func(t *testing.T) {
+		s, err := Parse([]byte(`false`))
+
+		require.Nil(t, err, "Error is not nil")
+		assert.Len(t, validate(t, s, `{}`), 1, "Violations not correct")
+	})
+}
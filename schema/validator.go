@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	metaPath    string = "/meta"
+	versionPath string = "/schema_version"
+)
+
+//Validator checks the meta of entries against the schema registered for their object type, action and schema version.
+//Entries without a schema version are not checked, entries with a version which has no schema are rejected.
+type Validator struct {
+	Registry *Registry
+}
+
+//Validate checks the meta of the entry against its schema.
+//The rules the meta breaks are returned as a domain.ValidationError.
+func (v Validator) Validate(ctx context.Context, e domain.Entry) error {
+	if e.SchemaVersion == 0 {
+		return nil
+	}
+
+	key := Key{ObjectType: e.ObjectType, Action: e.Action, Version: e.SchemaVersion}
+	s, ok := v.Registry.Lookup(key)
+
+	if !ok {
+		return invalid(Violation{Path: versionPath, Message: "has no schema registered for " + key.String()})
+	}
+
+	var meta interface{}
+
+	if len(e.Meta) > 0 {
+		err := json.Unmarshal(e.Meta, &meta)
+
+		if err != nil {
+			return invalid(Violation{Path: metaPath, Message: "is not valid JSON"})
+		}
+	}
+
+	violations := s.Validate(meta, metaPath)
+
+	if len(violations) > 0 {
+		return invalid(violations...)
+	}
+
+	return nil
+}
+
+func invalid(violations ...Violation) error {
+	fields := make([]domain.FieldError, len(violations))
+
+	for i, v := range violations {
+		fields[i] = domain.FieldError{Path: v.Path, Message: v.Message}
+	}
+
+	return &domain.ValidationError{Errors: fields}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (s HTTP) handleConsumers(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	health, err := s.Health.GetStreamHealth(r.Context())
+
+	if err != nil {
+		writeError(w, r, "Error getting stream health", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, health)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleConsumers(t *testing.T) {
+	t.Run("Report consumer lag", func(t *testing.T) {
+		health := &mock.TestHealthRepo{
+			GetStreamHealthReturnHealth: []domain.StreamHealth{
+				domain.StreamHealth{
+					Stream: "eventStream",
+					Length: 12,
+					LastID: "5000-1",
+					Consumers: []domain.ConsumerLag{
+						domain.ConsumerLag{Consumer: "dead", Position: "4000-0", Entries: 3, LagMillis: 1000},
+					},
+				},
+			},
+		}
+		s := HTTP{Repo: &mock.TestRepo{}, Health: health}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/admin/consumers", nil))
+
+		require.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+
+		var resp []domain.StreamHealth
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp), "Error unmarshaling response")
+
+		assert.Equal(t, health.GetStreamHealthReturnHealth, resp, "Wrong health")
+		assert.Contains(t, w.Body.String(), `"lag_ms":1000`, "Lag not serialized")
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Health: &mock.TestHealthRepo{GetStreamHealthReturnError: errors.New("some error")}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/admin/consumers", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "Wrong status code")
+	})
+}
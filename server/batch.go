@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/julienschmidt/httprouter"
+)
+
+const maxBatchSize int = 1000
+
+//batchResult reports the outcome of storing a single entry from a batch
+//Duplicate entries were stored before under the same idempotency key, ID is the one of the original entry.
+//Code and Error are the problem code and detail of entries which were not stored.
+type batchResult struct {
+	Index     int                 `json:"index"`
+	ID        string              `json:"id,omitempty"`
+	Duplicate bool                `json:"duplicate,omitempty"`
+	Code      string              `json:"code,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+//batchResponse is the body returned by the batch ingestion endpoint
+type batchResponse struct {
+	Stored     int           `json:"stored"`
+	Duplicates int           `json:"duplicates"`
+	Failed     int           `json:"failed"`
+	Results    []batchResult `json:"results"`
+
+	//malformed is the number of entries which could not be decoded
+	malformed int
+	//unstored is the number of valid entries which could not be added to the stream
+	unstored int
+}
+
+func (s HTTP) handleNewEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		logf(r, "Error reading request body: %s", err)
+		writeProblem(w, r, http.StatusBadRequest, codeMalformedBody, "body could not be read", nil)
+		return
+	}
+
+	items, err := splitBatch(body)
+
+	if err != nil {
+		logf(r, "Error splitting batch: %s", err)
+		writeProblem(w, r, http.StatusBadRequest, codeMalformedBody, bodyError(err), nil)
+		return
+	}
+
+	if len(items) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, codeEmptyBatch, "batch is empty", nil)
+		return
+	}
+
+	if len(items) > maxBatchSize {
+		detail := fmt.Sprintf("batch exceeds %d entries", maxBatchSize)
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, codeBatchTooLarge, detail, nil)
+		return
+	}
+
+	resp := batchResponse{
+		Results: make([]batchResult, len(items)),
+	}
+	valid := make([]domain.Entry, 0, len(items))
+	validIndexes := make([]int, 0, len(items))
+
+	for i, item := range items {
+		resp.Results[i].Index = i
+
+		var e domain.Entry
+		err = json.Unmarshal(item, &e)
+
+		if err != nil {
+			resp.Results[i].Code, resp.Results[i].Error = codeMalformedBody, bodyError(err)
+			resp.Failed++
+			resp.malformed++
+			continue
+		}
+
+		err = s.Validator.Validate(r.Context(), e)
+		invalid, ok := domain.AsValidationError(err)
+
+		if err != nil && !ok {
+			writeError(w, r, "Error validating entry", err)
+			return
+		}
+
+		if err != nil {
+			s.countValidationFailure()
+
+			resp.Results[i].Code, resp.Results[i].Error = codeInvalidEntry, "entry breaks validation rules"
+			resp.Results[i].Errors = invalid.Errors
+			resp.Failed++
+			continue
+		}
+
+		valid = append(valid, e)
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) > 0 {
+		start := time.Now()
+		results, err := s.Repo.AddEntries(r.Context(), valid)
+		s.observeAdd("batch", start)
+
+		//Nothing was stored and nothing else went wrong, the batch failed as a whole.
+		if err != nil && resp.Failed == 0 {
+			writeError(w, r, "Error adding entries to repo", err)
+			return
+		}
+
+		for i, result := range results {
+			s.addResult(r, &resp, validIndexes[i], valid[i], result)
+		}
+	}
+
+	writeJSON(w, batchStatus(resp), resp)
+}
+
+//addResult records the outcome of adding the entry at the index of the batch.
+//Only created entries are counted as ingested.
+func (s HTTP) addResult(r *http.Request, resp *batchResponse, index int, e domain.Entry, result domain.AddResult) {
+	res := &resp.Results[index]
+
+	switch {
+	case result.Err != nil:
+		logf(r, "Error adding entry %d to repo: %s", index, result.Err)
+
+		res.Code, res.Error = codeInternal, "entry could not be stored"
+
+		if errors.Is(result.Err, domain.ErrUnavailable) {
+			res.Code, res.Error = codeUnavailable, "storage is unavailable, retry later"
+		}
+
+		resp.Failed++
+		resp.unstored++
+	case result.Created:
+		res.ID = result.ID
+		resp.Stored++
+		s.countIngested(e)
+	default:
+		res.ID, res.Duplicate = result.ID, true
+		resp.Duplicates++
+	}
+}
+
+//splitBatch splits the body into raw entries.
+//The body is either a JSON array or newline delimited JSON objects.
+func splitBatch(body []byte) ([]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+
+	if len(body) > 0 && body[0] == '[' {
+		var items []json.RawMessage
+		err := json.Unmarshal(body, &items)
+
+		return items, err
+	}
+
+	items := []json.RawMessage{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+
+		if len(line) == 0 {
+			continue
+		}
+
+		items = append(items, json.RawMessage(append([]byte(nil), line...)))
+	}
+
+	return items, scanner.Err()
+}
+
+//batchStatus maps the batch outcome to the status code:
+//201 if every entry was stored, 200 if they were all stored before, 207 on partial success
+//or when entries failed for different reasons. If nothing was stored 422 when all the entries
+//were well formed but invalid, 400 otherwise.
+func batchStatus(resp batchResponse) int {
+	switch {
+	case resp.Failed == 0 && resp.Stored == 0:
+		return http.StatusOK
+	case resp.Failed == 0:
+		return http.StatusCreated
+	case resp.Stored+resp.Duplicates > 0 || resp.unstored > 0:
+		return http.StatusMultiStatus
+	case resp.malformed == 0:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
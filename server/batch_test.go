@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValidator struct{}
+
+func (v testValidator) Validate(ctx context.Context, e domain.Entry) error {
+	if e.Action == "" {
+		return &domain.ValidationError{Errors: []domain.FieldError{{Path: "/action", Message: "is required"}}}
+	}
+
+	return nil
+}
+
+func TestSplitBatch(t *testing.T) {
+	t.Run("JSON array", func(t *testing.T) {
+		items, err := splitBatch([]byte(` [{"action":"create"}, {"action":"delete"}] `))
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Len(t, items, 2, "Wrong number of items")
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		items, err := splitBatch([]byte("{\"action\":\"create\"}\n\n{\"action\":\"delete\"}\n"))
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Len(t, items, 2, "Wrong number of items")
+		assert.Equal(t, `{"action":"delete"}`, string(items[1]), "Wrong item")
+	})
+
+	t.Run("Malformed JSON array", func(t *testing.T) {
+		_, err := splitBatch([]byte(`[{"action":"create"}`))
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestHandleNewEntries(t *testing.T) {
+	t.Run("Partial success", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{ID: "1-0", Created: true}, {ID: "2-0", Created: true}},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		body := "{\"action\":\"create\"}\n{\"object_id\":1}\n{\"action\":\"delete\"}\nnot json\n"
+		req := httptest.NewRequest("POST", "/entries", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusMultiStatus, w.Code, "Wrong status code")
+		assert.Len(t, repo.AddEntriesEntries, 2, "Stored wrong number of entries")
+
+		var resp batchResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp), "Error unmarshaling response")
+
+		assert.Equal(t, 2, resp.Stored, "Wrong stored count")
+		assert.Equal(t, 2, resp.Failed, "Wrong failed count")
+		assert.Equal(t, "1-0", resp.Results[0].ID, "Wrong ID for first entry")
+		assert.NotEmpty(t, resp.Results[1].Error, "No error for invalid entry")
+		assert.Equal(t, []domain.FieldError{{Path: "/action", Message: "is required"}}, resp.Results[1].Errors, "Wrong field errors")
+		assert.Equal(t, "2-0", resp.Results[2].ID, "Wrong ID for third entry")
+		assert.Equal(t, codeMalformedBody, resp.Results[3].Code, "Wrong code for malformed entry")
+	})
+
+	t.Run("All entries stored", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{ID: "1-0", Created: true}},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		req := httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"action":"create"}]`))
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code, "Wrong status code")
+	})
+
+	t.Run("All entries invalid", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+		req := httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"object_id":1}]`))
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "Wrong status code")
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+		req := httptest.NewRequest("POST", "/entries", strings.NewReader(`[]`))
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code")
+		assert.Equal(t, codeEmptyBatch, readProblem(t, w).Code, "Wrong code")
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{ID: "1-0", Created: true}, {ID: "0-1"}},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		body := `[{"action":"create","idempotency_key":"a"},{"action":"create","idempotency_key":"b"}]`
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entries", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusCreated, w.Code, "Wrong status code")
+
+		var resp batchResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp), "Error unmarshaling response")
+
+		assert.Equal(t, 1, resp.Stored, "Duplicate counted as stored")
+		assert.Equal(t, 1, resp.Duplicates, "Wrong duplicates count")
+		assert.False(t, resp.Results[0].Duplicate, "Created entry marked as duplicate")
+		assert.True(t, resp.Results[1].Duplicate, "Duplicate not marked")
+		assert.Equal(t, "0-1", resp.Results[1].ID, "Wrong ID for duplicate")
+	})
+
+	t.Run("All entries stored before", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{ID: "0-1"}},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"action":"create","idempotency_key":"a"}]`)))
+
+		assert.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+	})
+
+	t.Run("Some entries not stored", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{
+				{ID: "1-0", Created: true},
+				{Err: fmt.Errorf("AddEntries: %w: i/o timeout", domain.ErrUnavailable)},
+				{Err: errors.New("AddEntries: ERR some internal detail")},
+			},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"action":"create"},{"action":"update"},{"action":"delete"}]`)))
+
+		require.Equal(t, http.StatusMultiStatus, w.Code, "Wrong status code")
+		assert.NotContains(t, w.Body.String(), "internal detail", "Error details leaked")
+
+		var resp batchResponse
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp), "Error unmarshaling response")
+
+		assert.Equal(t, 1, resp.Stored, "Wrong stored count")
+		assert.Equal(t, 2, resp.Failed, "Wrong failed count")
+		assert.Equal(t, codeUnavailable, resp.Results[1].Code, "Wrong code for unavailable storage")
+		assert.Equal(t, codeInternal, resp.Results[2].Code, "Wrong code for storage error")
+	})
+
+	t.Run("Invalid entries and storage errors", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{Err: errors.New("some error")}},
+			AddEntriesReturnError:   errors.New("some error"),
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"action":"create"},{"object_id":1}]`)))
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code, "Wrong status code")
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntriesReturnResults: []domain.AddResult{{Err: errors.New("some error")}},
+			AddEntriesReturnError:   errors.New("some error"),
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		req := httptest.NewRequest("POST", "/entries", strings.NewReader(`[{"action":"create"}]`))
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "Wrong status code")
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/julienschmidt/httprouter"
+)
+
+const defaultDeadEntriesCount int64 = 100
+
+func (s HTTP) handleListDeadEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	count := defaultDeadEntriesCount
+
+	if c := r.URL.Query().Get("count"); c != "" {
+		var err error
+		count, err = strconv.ParseInt(c, 10, 64)
+
+		if err != nil || count <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, "count must be a positive integer", nil)
+			return
+		}
+	}
+
+	start := r.URL.Query().Get("start")
+
+	if err := checkRangeID("start", start); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, err.Error(), nil)
+		return
+	}
+
+	entries, err := s.DeadLetters.GetDeadEntries(r.Context(), start, count)
+
+	if err != nil {
+		writeError(w, r, "Error getting dead entries", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s HTTP) handleGetDeadEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ID := p.ByName("id")
+
+	if !validID(w, r, ID) {
+		return
+	}
+
+	entry, err := s.DeadLetters.GetDeadEntry(r.Context(), ID)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "dead letter entry not found", nil)
+		return
+	}
+
+	if err != nil {
+		writeError(w, r, "Error getting dead entry", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s HTTP) handleReplayDeadEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ID := p.ByName("id")
+
+	if !validID(w, r, ID) {
+		return
+	}
+
+	err := s.DeadLetters.ReplayDeadEntry(r.Context(), ID)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "dead letter entry not found", nil)
+		return
+	}
+
+	if err != nil {
+		writeError(w, r, "Error replaying dead entry", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s HTTP) handlePurgeDeadEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	var IDs []string
+
+	if ID := p.ByName("id"); ID != "" {
+		if !validID(w, r, ID) {
+			return
+		}
+
+		IDs = append(IDs, ID)
+	}
+
+	err := s.DeadLetters.PurgeDeadEntries(r.Context(), IDs...)
+
+	if err != nil {
+		writeError(w, r, "Error purging dead entries", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+
+	if err != nil {
+		log.Printf("Error writing response: %s", err)
+	}
+}
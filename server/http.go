@@ -2,25 +2,74 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
 	"github.com/julienschmidt/httprouter"
 )
 
 //HTTP is a HTTP server that will handle incoming requests
 type HTTP struct {
-	router    http.Handler
-	Repo      domain.EntryRepository
-	Validator domain.EntryValidator
+	router      http.Handler
+	Repo        domain.EntryRepository
+	Validator   domain.EntryValidator
+	DeadLetters domain.DeadLetterRepository
+
+	//Health reports the lag of the consumers on /admin/consumers when set.
+	Health domain.HealthRepository
+
+	//Metrics are served on /metrics when set.
+	Metrics *metrics.Registry
+
+	//Done is closed when the server is shutting down so long-lived requests can finish.
+	Done <-chan struct{}
+}
+
+const idempotencyKeyHeader string = "Idempotency-Key"
+
+//entryResponse is the body returned for a single entry.
+//Entry.ID is not serialized so the ID is exposed alongside the entry fields.
+type entryResponse struct {
+	ID string `json:"id"`
+	*domain.Entry
 }
 
 func (s *HTTP) setRouter() {
 	router := httprouter.New()
 
 	router.Handle("POST", "/entry", s.handleNewEntry)
+	router.Handle("GET", "/entry/:id", s.handleGetEntry)
+	router.Handle("GET", "/entries", s.handleQueryEntries)
+	router.Handle("POST", "/entries", s.handleNewEntries)
+	router.Handle("GET", "/stream", s.handleStream)
+
+	if s.DeadLetters != nil {
+		router.Handle("GET", "/dlq", s.handleListDeadEntries)
+		router.Handle("DELETE", "/dlq", s.handlePurgeDeadEntries)
+		router.Handle("GET", "/dlq/:id", s.handleGetDeadEntry)
+		router.Handle("DELETE", "/dlq/:id", s.handlePurgeDeadEntries)
+		router.Handle("POST", "/dlq/:id/replay", s.handleReplayDeadEntry)
+	}
+
+	if s.Health != nil {
+		router.Handle("GET", "/admin/consumers", s.handleConsumers)
+	}
+
+	if s.Metrics != nil {
+		router.Handler("GET", "/metrics", s.Metrics)
+	}
+
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "", nil)
+	})
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "", nil)
+	})
+
 	s.router = router
 }
 
@@ -29,39 +78,78 @@ func (s HTTP) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		s.setRouter()
 	}
 
-	s.router.ServeHTTP(w, req)
+	s.router.ServeHTTP(w, withRequestID(w, req))
 }
 
 func (s HTTP) handleNewEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	body, err := ioutil.ReadAll(r.Body)
 
 	if err != nil {
-		log.Printf("Error reading request body: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logf(r, "Error reading request body: %s", err)
+		writeProblem(w, r, http.StatusBadRequest, codeMalformedBody, "body could not be read", nil)
 		return
 	}
 
 	var e domain.Entry
 	err = json.Unmarshal(body, &e)
 	if err != nil {
-		log.Printf("Error unmarshaling body: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logf(r, "Error unmarshaling body: %s", err)
+		writeProblem(w, r, http.StatusBadRequest, codeMalformedBody, bodyError(err), nil)
 		return
 	}
 
-	err = s.Validator.Validate(e)
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		e.IdempotencyKey = key
+	}
+
+	err = s.Validator.Validate(r.Context(), e)
 	if err != nil {
-		log.Printf("Error validating entry: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.countValidationFailure()
+		writeInvalidEntry(w, r, err)
+		return
+	}
+
+	start := time.Now()
+	ID, created, err := s.Repo.AddEntryOnce(r.Context(), e)
+	s.observeAdd("entry", start)
+	if err != nil {
+		writeError(w, r, "Error adding entry to repo", err)
+		return
+	}
+
+	status := http.StatusCreated
+
+	//The entry was already added by a previous request with the same idempotency key.
+	if !created {
+		status = http.StatusOK
+	}
+
+	if created {
+		s.countIngested(e)
+	}
+
+	w.Header().Set("Location", "/entry/"+ID)
+	writeJSON(w, status, entryResponse{ID: ID})
+}
+
+func (s HTTP) handleGetEntry(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ID := p.ByName("id")
+
+	if !validID(w, r, ID) {
+		return
+	}
+
+	e, err := s.Repo.GetEntry(r.Context(), ID)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, codeNotFound, "entry not found", nil)
 		return
 	}
 
-	err = s.Repo.AddEntry(e)
 	if err != nil {
-		log.Printf("Error adding entry to repo: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, "Error getting entry from repo", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, http.StatusOK, entryResponse{ID: e.ID, Entry: &e})
 }
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errorValidator struct{}
+
+func (v errorValidator) Validate(ctx context.Context, e domain.Entry) error {
+	return errors.New("some error")
+}
+
+//memoryRepo keeps the added entries in memory so they can be fetched back
+type memoryRepo struct {
+	*mock.TestRepo
+	entries map[string]domain.Entry
+}
+
+func (m *memoryRepo) AddEntryOnce(ctx context.Context, e domain.Entry) (string, bool, error) {
+	e.ID = fmt.Sprintf("%d-0", len(m.entries)+1)
+	m.entries[e.ID] = e
+
+	return e.ID, true, nil
+}
+
+func (m *memoryRepo) GetEntry(ctx context.Context, ID string) (domain.Entry, error) {
+	e, ok := m.entries[ID]
+
+	if !ok {
+		return domain.Entry{}, domain.ErrNotFound
+	}
+
+	return e, nil
+}
+
+func readProblem(t *testing.T, w *httptest.ResponseRecorder) problem {
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"), "Wrong content type")
+
+	var p problem
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &p), "Error unmarshaling problem")
+
+	assert.Equal(t, w.Code, p.Status, "Problem status not the response status")
+	assert.Equal(t, w.Header().Get(requestIDHeader), p.RequestID, "Problem request ID not the header one")
+
+	return p
+}
+
+func TestHandleNewEntry(t *testing.T) {
+	t.Run("Entry created", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntryOnceReturnID:      "1-0",
+			AddEntryOnceReturnCreated: true,
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"action":"create","meta":{"name":"my object"}}`)))
+
+		assert.Equal(t, http.StatusCreated, w.Code, "Wrong status code")
+		assert.JSONEq(t, `{"id":"1-0"}`, w.Body.String(), "Wrong body")
+		assert.Equal(t, `{"name":"my object"}`, string(repo.AddEntryOnceEntry.Meta), "Meta not stored")
+	})
+
+	t.Run("Location of the created entry", func(t *testing.T) {
+		s := HTTP{Repo: &memoryRepo{&mock.TestRepo{}, map[string]domain.Entry{}}, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"object_id":3,"object_type":2,"action":"create"}`)))
+
+		require.Equal(t, http.StatusCreated, w.Code, "Wrong status code")
+
+		location := w.Header().Get("Location")
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", location, nil))
+
+		require.Equal(t, http.StatusOK, w.Code, "Entry not found at its location %s", location)
+		assert.JSONEq(t, `{"id":"1-0","object_id":3,"object_type":2,"action":"create"}`, w.Body.String(), "Wrong body")
+	})
+
+	t.Run("Malformed body", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+		for body, detail := range map[string]string{
+			`{"object_id":`:     "body is not valid JSON at offset 13",
+			`{"object_id":"1"}`: "object_id must be a number",
+		} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(body)))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code")
+
+			p := readProblem(t, w)
+			assert.Equal(t, codeMalformedBody, p.Code, "Wrong code")
+			assert.Equal(t, detail, p.Detail, "Wrong detail")
+		}
+	})
+
+	t.Run("Invalid entry", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"object_id":1}`)))
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code, "Wrong status code")
+
+		p := readProblem(t, w)
+		assert.Equal(t, codeInvalidEntry, p.Code, "Wrong code")
+		assert.Equal(t, "/entry", p.Instance, "Wrong instance")
+		assert.Equal(t, []domain.FieldError{{Path: "/action", Message: "is required"}}, p.Errors, "Wrong field errors")
+	})
+
+	t.Run("Validator error", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: errorValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"object_id":1}`)))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code, "Wrong status code")
+		assert.Equal(t, codeInternal, readProblem(t, w).Code, "Wrong code")
+	})
+
+	t.Run("Storage unavailable", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntryOnceReturnError: fmt.Errorf("AddEntryOnce: %w: dial tcp :6379: connection refused", domain.ErrUnavailable),
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"action":"create"}`)))
+
+		require.Equal(t, http.StatusServiceUnavailable, w.Code, "Wrong status code")
+		assert.Equal(t, retryAfter, w.Header().Get("Retry-After"), "Wrong Retry-After header")
+
+		p := readProblem(t, w)
+		assert.Equal(t, codeUnavailable, p.Code, "Wrong code")
+		assert.NotContains(t, p.Detail, "6379", "Error details leaked")
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntryOnceReturnError: errors.New("AddEntryOnce: ERR some internal detail"),
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(`{"action":"create"}`)))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code, "Wrong status code")
+		assert.NotContains(t, w.Body.String(), "internal detail", "Error details leaked")
+	})
+}
+
+func TestHandleGetEntry(t *testing.T) {
+	t.Run("Entry found", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			GetEntryReturnEntry: domain.Entry{ID: "1-0", ObjectID: 1, ObjectType: 2, Action: "create"},
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/entry/1-0", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+		assert.Equal(t, "1-0", repo.GetEntryID, "Wrong ID")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		repo := &mock.TestRepo{}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/entry/abc", nil))
+
+		require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code")
+		assert.Equal(t, codeInvalidParameter, readProblem(t, w).Code, "Wrong code")
+		assert.Empty(t, repo.GetEntryID, "Repository called with an invalid ID")
+	})
+
+	t.Run("Entry not found", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			GetEntryReturnError: fmt.Errorf("GetEntry: %w", domain.ErrNotFound),
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/entry/1-0", nil))
+
+		require.Equal(t, http.StatusNotFound, w.Code, "Wrong status code")
+		assert.Equal(t, codeNotFound, readProblem(t, w).Code, "Wrong code")
+	})
+}
+
+func TestHandleQueryEntries(t *testing.T) {
+	t.Run("Range boundaries", func(t *testing.T) {
+		repo := &mock.TestRepo{}
+		s := HTTP{Repo: repo, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/entries?from=-&to=1543410000000-1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+		assert.Equal(t, "-", repo.QueryEntriesQuery.From, "Wrong from")
+		assert.Equal(t, "1543410000000-1", repo.QueryEntriesQuery.To, "Wrong to")
+	})
+
+	t.Run("Invalid range boundaries", func(t *testing.T) {
+		for _, URL := range []string{"/entries?from=abc", "/entries?to=1-x", "/entries?from=1-"} {
+			repo := &mock.TestRepo{}
+			s := HTTP{Repo: repo, Validator: testValidator{}}
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("GET", URL, nil))
+
+			require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code for %s", URL)
+			assert.Equal(t, codeInvalidParameter, readProblem(t, w).Code, "Wrong code")
+			assert.Equal(t, domain.EntryQuery{}, repo.QueryEntriesQuery, "Repository called with an invalid range")
+		}
+	})
+}
+
+func TestDeadLetterHandlers(t *testing.T) {
+	t.Run("Invalid ID", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/dlq/abc", nil),
+			httptest.NewRequest("POST", "/dlq/1-x/replay", nil),
+			httptest.NewRequest("DELETE", "/dlq/abc", nil),
+		} {
+			deadLetters := &mock.TestDeadLetterRepo{}
+			s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}, DeadLetters: deadLetters}
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code for %s %s", req.Method, req.URL)
+			assert.Equal(t, codeInvalidParameter, readProblem(t, w).Code, "Wrong code")
+			assert.Empty(t, deadLetters.GetDeadEntryID+deadLetters.ReplayDeadEntryID, "Repository called with an invalid ID")
+			assert.Nil(t, deadLetters.PurgeDeadEntriesIDs, "Repository called with an invalid ID")
+		}
+	})
+
+	t.Run("Invalid start", func(t *testing.T) {
+		deadLetters := &mock.TestDeadLetterRepo{}
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}, DeadLetters: deadLetters}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlq?start=abc", nil))
+
+		require.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code")
+		assert.Equal(t, codeInvalidParameter, readProblem(t, w).Code, "Wrong code")
+		assert.Empty(t, deadLetters.GetDeadEntriesStart, "Repository called with an invalid start")
+	})
+
+	t.Run("Entry not found", func(t *testing.T) {
+		deadLetters := &mock.TestDeadLetterRepo{
+			GetDeadEntryReturnError:    fmt.Errorf("GetDeadEntry: %w", domain.ErrNotFound),
+			ReplayDeadEntryReturnError: fmt.Errorf("ReplayDeadEntry: %w", domain.ErrNotFound),
+		}
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}, DeadLetters: deadLetters}
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/dlq/1-0", nil),
+			httptest.NewRequest("POST", "/dlq/1-0/replay", nil),
+		} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusNotFound, w.Code, "Wrong status code for %s %s", req.Method, req.URL)
+			assert.Equal(t, codeNotFound, readProblem(t, w).Code, "Wrong code")
+		}
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+	t.Run("Client request ID is echoed", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/entries?count=0", nil)
+		req.Header.Set(requestIDHeader, "my-request:1")
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, "my-request:1", w.Header().Get(requestIDHeader), "Request ID not echoed")
+		assert.Equal(t, "my-request:1", readProblem(t, w).RequestID, "Request ID not in the problem")
+	})
+
+	t.Run("Unsafe request ID is replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/entries", nil)
+		req.Header.Set(requestIDHeader, "id\nforged log line")
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.NotEmpty(t, w.Header().Get(requestIDHeader), "Request ID not generated")
+		assert.NotContains(t, w.Header().Get(requestIDHeader), "forged", "Unsafe request ID used")
+	})
+
+	t.Run("Unknown route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/unknown", nil))
+
+		require.Equal(t, http.StatusNotFound, w.Code, "Wrong status code")
+		assert.Equal(t, codeNotFound, readProblem(t, w).Code, "Wrong code")
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+//countIngested counts the stored entries by action and object type.
+func (s HTTP) countIngested(entries ...domain.Entry) {
+	if s.Metrics == nil {
+		return
+	}
+
+	c := s.Metrics.Counter("grs_entries_ingested_total", "Entries stored by the publisher", "action", "object_type")
+
+	for _, e := range entries {
+		c.Inc(e.Action, strconv.Itoa(e.ObjectType))
+	}
+}
+
+//countValidationFailure counts an entry rejected by the validator.
+func (s HTTP) countValidationFailure() {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.Counter("grs_validation_failures_total", "Entries rejected by the validator").Inc()
+}
+
+//observeAdd records how long adding to the stream took, operation is either entry or batch.
+func (s HTTP) observeAdd(operation string, start time.Time) {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.Histogram(
+		"grs_xadd_duration_seconds",
+		"Time spent adding entries to the stream",
+		nil,
+		"operation",
+	).Observe(time.Since(start).Seconds(), operation)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/antekresic/grs/metrics"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("Count ingested and rejected entries", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			AddEntryOnceReturnID:      "1-0",
+			AddEntryOnceReturnCreated: true,
+		}
+		s := HTTP{Repo: repo, Validator: testValidator{}, Metrics: metrics.NewRegistry()}
+
+		for _, body := range []string{`{"action":"create","object_type":2}`, `{"object_type":2}`} {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", "/entry", strings.NewReader(body)))
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		require.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+		assert.Contains(t, w.Body.String(), `grs_entries_ingested_total{action="create",object_type="2"} 1`, "Ingested entry not counted")
+		assert.Contains(t, w.Body.String(), "grs_validation_failures_total 1\n", "Validation failure not counted")
+		assert.Contains(t, w.Body.String(), `grs_xadd_duration_seconds_count{operation="entry"} 1`, "Add latency not observed")
+	})
+
+	t.Run("No metrics endpoint without a registry", func(t *testing.T) {
+		s := HTTP{Repo: &mock.TestRepo{}, Validator: testValidator{}}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code, "Wrong status code")
+	})
+}
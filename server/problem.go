@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+
+	"github.com/antekresic/grs/domain"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	problemContentType string = "application/problem+json"
+	problemType        string = "about:blank"
+
+	requestIDHeader    string = "X-Request-ID"
+	maxRequestIDLength int    = 128
+
+	//retryAfter is the number of seconds clients are asked to wait when the storage is unavailable
+	retryAfter string = "5"
+)
+
+//Problem codes are machine readable identifiers of the errors returned by the server.
+const (
+	codeMalformedBody    string = "malformed_body"
+	codeInvalidEntry     string = "invalid_entry"
+	codeInvalidParameter string = "invalid_parameter"
+	codeEmptyBatch       string = "empty_batch"
+	codeBatchTooLarge    string = "batch_too_large"
+	codeNotFound         string = "not_found"
+	codeMethodNotAllowed string = "method_not_allowed"
+	codeUnavailable      string = "storage_unavailable"
+	codeInternal         string = "internal_error"
+)
+
+//problem is an error response body in the RFC 7807 problem details format.
+//Code identifies the problem for machines, Errors lists the validation rules broken by single fields.
+type problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+type contextKey int
+
+const requestIDKey contextKey = 0
+
+//writeProblem writes a problem details response.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields []domain.FieldError) {
+	p := problem{
+		Type:      problemType,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestID(r.Context()),
+		Errors:    fields,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(p)
+
+	if err != nil {
+		logf(r, "Error writing response: %s", err)
+	}
+}
+
+//writeError logs an error returned by a repository and writes the problem for it.
+//The error itself is not exposed, it is found in the logs by the request ID.
+func writeError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logf(r, "%s: %s", message, err)
+
+	if errors.Is(err, domain.ErrUnavailable) {
+		w.Header().Set("Retry-After", retryAfter)
+		writeProblem(w, r, http.StatusServiceUnavailable, codeUnavailable, "storage is unavailable, retry later", nil)
+		return
+	}
+
+	writeProblem(w, r, http.StatusInternalServerError, codeInternal, "", nil)
+}
+
+//validID checks the stream ID given in the path and writes the problem if it is not one.
+func validID(w http.ResponseWriter, r *http.Request, ID string) bool {
+	_, _, err := domain.ParseID(ID)
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, "id must be a stream ID", nil)
+		return false
+	}
+
+	return true
+}
+
+//writeInvalidEntry writes the problem for an entry which failed validation.
+//Errors of validators which could not check the entry are internal errors.
+func writeInvalidEntry(w http.ResponseWriter, r *http.Request, err error) {
+	invalid, ok := domain.AsValidationError(err)
+
+	if !ok {
+		writeError(w, r, "Error validating entry", err)
+		return
+	}
+
+	logf(r, "Invalid entry: %s", err)
+	writeProblem(w, r, http.StatusUnprocessableEntity, codeInvalidEntry, "entry breaks validation rules", invalid.Errors)
+}
+
+//bodyError describes why a body could not be decoded without exposing the decoder internals.
+func bodyError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("body is not valid JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("%s must be %s", typeErr.Field, jsonType(typeErr.Type))
+	default:
+		return "body is not a valid entry"
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	default:
+		return "of another type"
+	}
+}
+
+//withRequestID adds the request ID to the context of the request and the response headers.
+//The ID given by the client is used when it is safe to log, a new one is generated otherwise.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	ID := r.Header.Get(requestIDHeader)
+
+	if !validRequestID(ID) {
+		ID = uuid.NewV4().String()
+	}
+
+	w.Header().Set(requestIDHeader, ID)
+
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey, ID))
+}
+
+func validRequestID(ID string) bool {
+	if ID == "" || len(ID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range ID {
+		valid := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == ':'
+
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requestID(ctx context.Context) string {
+	ID, _ := ctx.Value(requestIDKey).(string)
+	return ID
+}
+
+//logf logs the message prefixed with the ID of the request.
+func logf(r *http.Request, format string, args ...interface{}) {
+	log.Printf("[%s] "+format, append([]interface{}{requestID(r.Context())}, args...)...)
+}
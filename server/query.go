@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultQueryCount int64 = 100
+	maxQueryCount     int64 = 1000
+)
+
+//queryResponse is the body returned by the stream query endpoint
+type queryResponse struct {
+	Entries []entryResponse `json:"entries"`
+	Next    string          `json:"next,omitempty"`
+}
+
+func (s HTTP) handleQueryEntries(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	q, err := parseEntryQuery(r.URL.Query())
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, err.Error(), nil)
+		return
+	}
+
+	page, err := s.Repo.QueryEntries(r.Context(), q)
+
+	if err != nil {
+		writeError(w, r, "Error querying entries", err)
+		return
+	}
+
+	resp := queryResponse{
+		Entries: make([]entryResponse, len(page.Entries)),
+		Next:    page.Next,
+	}
+
+	for i := range page.Entries {
+		resp.Entries[i] = entryResponse{ID: page.Entries[i].ID, Entry: &page.Entries[i]}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseEntryQuery(values url.Values) (domain.EntryQuery, error) {
+	q := domain.EntryQuery{
+		From:   values.Get("from"),
+		To:     values.Get("to"),
+		Count:  defaultQueryCount,
+		Action: values.Get("action"),
+	}
+
+	err := checkRangeID("from", q.From)
+
+	if err != nil {
+		return q, err
+	}
+
+	err = checkRangeID("to", q.To)
+
+	if err != nil {
+		return q, err
+	}
+
+	if c := values.Get("count"); c != "" {
+		q.Count, err = strconv.ParseInt(c, 10, 64)
+
+		if err != nil || q.Count <= 0 || q.Count > maxQueryCount {
+			return q, fmt.Errorf("count must be between 1 and %d", maxQueryCount)
+		}
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Reverse = true
+	default:
+		return q, fmt.Errorf("order must be asc or desc")
+	}
+
+	if v := values.Get("object_id"); v != "" {
+		q.ObjectID, err = strconv.Atoi(v)
+
+		if err != nil {
+			return q, fmt.Errorf("object_id must be an integer")
+		}
+	}
+
+	if v := values.Get("object_type"); v != "" {
+		q.ObjectType, err = strconv.Atoi(v)
+
+		if err != nil {
+			return q, fmt.Errorf("object_type must be an integer")
+		}
+	}
+
+	return q, nil
+}
+
+//checkRangeID makes sure a range boundary is a stream ID, "-" or "+" before it reaches Redis.
+//Empty boundaries are left open.
+func checkRangeID(name, ID string) error {
+	if ID == "" || ID == "-" || ID == "+" {
+		return nil
+	}
+
+	if _, _, err := domain.ParseID(ID); err != nil {
+		return fmt.Errorf("%s must be a stream ID", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	lastEventIDHeader string        = "Last-Event-ID"
+	keepAliveInterval time.Duration = 15 * time.Second
+	tailCount         int64         = 100
+)
+
+//handleStream pushes new entries to the client as Server-Sent Events.
+//Event IDs are stream IDs so clients can resume with the Last-Event-ID header.
+func (s HTTP) handleStream(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, codeInternal, "streaming is not supported", nil)
+		return
+	}
+
+	q, err := parseEntryQuery(r.URL.Query())
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, err.Error(), nil)
+		return
+	}
+
+	q.Count, q.Reverse, q.To = tailCount, false, ""
+	//Without Last-Event-ID the client only receives entries added after it connected.
+	q.From = r.Header.Get(lastEventIDHeader)
+
+	if q.From != "" {
+		if _, _, err := domain.ParseID(q.From); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, codeInvalidParameter, lastEventIDHeader+" must be a stream ID", nil)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lastWrite := time.Now()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.Done:
+			return
+		default:
+		}
+
+		page, err := s.Repo.TailEntries(r.Context(), q)
+
+		if err != nil {
+			logf(r, "Error tailing entries: %s", err)
+			return
+		}
+
+		q.From = page.Next
+
+		for i := range page.Entries {
+			err = writeEvent(w, page.Entries[i])
+
+			if err != nil {
+				logf(r, "Error writing event: %s", err)
+				return
+			}
+		}
+
+		if len(page.Entries) == 0 && time.Since(lastWrite) < keepAliveInterval {
+			continue
+		}
+
+		//Comments keep idle connections from being closed by proxies.
+		if len(page.Entries) == 0 {
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		flusher.Flush()
+		lastWrite = time.Now()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e domain.Entry) error {
+	data, err := json.Marshal(entryResponse{ID: e.ID, Entry: &e})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: entry\ndata: %s\n\n", e.ID, data)
+
+	return err
+}
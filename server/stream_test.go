@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleStream(t *testing.T) {
+	t.Run("Resume from Last-Event-ID", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			TailEntriesReturnError: errors.New("some error"),
+		}
+		s := HTTP{Repo: repo}
+
+		req := httptest.NewRequest("GET", "/stream?action=create", nil)
+		req.Header.Set(lastEventIDHeader, "1-0")
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Wrong status code")
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"), "Wrong content type")
+		assert.Equal(t, "1-0", repo.TailEntriesQuery.From, "Did not resume from Last-Event-ID")
+		assert.Equal(t, "create", repo.TailEntriesQuery.Action, "Did not filter by action")
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		repo := &mock.TestRepo{}
+		s := HTTP{Repo: repo}
+
+		req := httptest.NewRequest("GET", "/stream", nil)
+		req.Header.Set(lastEventIDHeader, "abc")
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "Wrong status code")
+		assert.Equal(t, codeInvalidParameter, readProblem(t, w).Code, "Wrong code")
+		assert.Equal(t, domain.EntryQuery{}, repo.TailEntriesQuery, "Tailed from an invalid ID")
+	})
+
+	t.Run("Start after the newest entry", func(t *testing.T) {
+		repo := &mock.TestRepo{
+			TailEntriesReturnError: errors.New("some error"),
+		}
+		s := HTTP{Repo: repo}
+
+		req := httptest.NewRequest("GET", "/stream", nil)
+		w := httptest.NewRecorder()
+
+		s.ServeHTTP(w, req)
+
+		assert.Empty(t, repo.TailEntriesQuery.From, "Did not start after the newest entry")
+	})
+}
+
+func TestWriteEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := writeEvent(w, domain.Entry{ID: "1-0", ObjectID: 3, ObjectType: 2, Action: "create", Meta: json.RawMessage(`{"name":"my object"}`)})
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Equal(
+		t,
+		"id: 1-0\nevent: entry\ndata: {\"id\":\"1-0\",\"object_id\":3,\"object_type\":2,\"action\":\"create\",\"meta\":{\"name\":\"my object\"}}\n\n",
+		w.Body.String(),
+		"Event not correct",
+	)
+}
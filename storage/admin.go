@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/antekresic/grs/domain"
+)
+
+//evictCursorScript removes the cursor, tombstoning its epoch so a consumer still holding it is fenced out.
+//The tombstone never expires, an expired one would read as epoch 0 and let the consumer in again.
+//Returns 1 if the cursor was removed, 0 if it is alive and not forced, -1 if it does not exist.
+const evictCursorScript = `
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+if ARGV[2] == '0' and redis.call('EXISTS', KEYS[3]) == 1 then
+	return 0
+end
+local epoch = tonumber(redis.call('GET', KEYS[4]) or '0')
+redis.call('SREM', KEYS[1], ARGV[1])
+redis.call('DEL', KEYS[2], KEYS[3])
+redis.call('SET', KEYS[4], epoch + 1)
+return 1
+`
+
+//setCursorPositionScript moves the position of the cursor on a single stream and increments its epoch,
+//so a consumer still holding the cursor is fenced out instead of overwriting the position.
+//Positions stored before routing was introduced hold the ID on the default stream, given in ARGV[3].
+//Returns 1 if the position was set, 0 if it is alive and not forced, -1 if it does not exist.
+const setCursorPositionScript = `
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+if ARGV[2] == '0' and redis.call('EXISTS', KEYS[3]) == 1 then
+	return 0
+end
+local value = redis.call('GET', KEYS[2])
+local positions = {}
+if value and string.sub(value, 1, 1) == '{' then
+	positions = cjson.decode(value)
+elseif value then
+	positions[ARGV[3]] = value
+end
+positions[ARGV[4]] = ARGV[5]
+redis.call('SET', KEYS[2], cjson.encode(positions))
+redis.call('DEL', KEYS[3])
+redis.call('INCR', KEYS[4])
+return 1
+`
+
+//EvictCursor removes the cursor of a consumer, its position is lost.
+//Cursors of live consumers are only removed when forced, the consumer is then fenced out.
+//Returns domain.ErrNotFound if there is no such cursor and domain.ErrCursorAlive if it is alive.
+func (r RedisRepository) EvictCursor(ctx context.Context, name string, force bool) error {
+	result, err := r.client(ctx).Eval(
+		evictCursorScript,
+		[]string{r.consumerSet(), r.lastPosition(name), r.heart(name), r.epoch(name)},
+		name,
+		forceArg(force),
+	).Int64()
+
+	if err != nil {
+		return wrapError("EvictCursor", err)
+	}
+
+	return cursorScriptError(result)
+}
+
+//SetCursorPosition sets the ID of the last entry processed by the cursor on the stream,
+//the consumer which takes the cursor over continues after it.
+//Cursors of live consumers are only changed when forced, the consumer is then fenced out.
+//Returns domain.ErrNotFound if there is no such cursor and domain.ErrCursorAlive if it is alive.
+func (r RedisRepository) SetCursorPosition(ctx context.Context, name, stream, ID string, force bool) error {
+	_, _, err := domain.ParseID(ID)
+
+	if err != nil {
+		return wrapError("SetCursorPosition", err)
+	}
+
+	result, err := r.client(ctx).Eval(
+		setCursorPositionScript,
+		[]string{r.consumerSet(), r.lastPosition(name), r.heart(name), r.epoch(name)},
+		name,
+		forceArg(force),
+		r.streamName(),
+		stream,
+		ID,
+	).Int64()
+
+	if err != nil {
+		return wrapError("SetCursorPosition", err)
+	}
+
+	return cursorScriptError(result)
+}
+
+//cursorScriptError maps the result of the cursor admin scripts to an error.
+func cursorScriptError(result int64) error {
+	switch result {
+	case -1:
+		return domain.ErrNotFound
+	case 0:
+		return domain.ErrCursorAlive
+	default:
+		return nil
+	}
+}
+
+func forceArg(force bool) string {
+	if force {
+		return "1"
+	}
+
+	return "0"
+}
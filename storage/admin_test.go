@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvictCursor(t *testing.T) {
+	t.Run("Evicted", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(1), nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.EvictCursor(context.Background(), "myName", false)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			[]string{"consumers", "lastPosition:myName", "heart:myName", "epoch:myName"},
+			mockClient.EvalKeys,
+			"Keys not correct",
+		)
+		assert.Equal(t, []interface{}{"myName", "0"}, mockClient.EvalArgs, "Args not correct")
+	})
+
+	t.Run("Alive", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(0), nil),
+		}
+
+		err := RedisRepository{Client: mockClient}.EvictCursor(context.Background(), "myName", false)
+
+		assert.Equal(t, domain.ErrCursorAlive, err, "Error not correct")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(-1), nil),
+		}
+
+		err := RedisRepository{Client: mockClient}.EvictCursor(context.Background(), "myName", true)
+
+		assert.Equal(t, domain.ErrNotFound, err, "Error not correct")
+		assert.Equal(t, "1", mockClient.EvalArgs[1], "Force not passed")
+	})
+
+	t.Run("Redis error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		err := RedisRepository{Client: mockClient}.EvictCursor(context.Background(), "myName", false)
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestSetCursorPosition(t *testing.T) {
+	t.Run("Position set", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(1), nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.SetCursorPosition(context.Background(), "myName", "events:3", "5-1", true)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			[]string{"consumers", "lastPosition:myName", "heart:myName", "epoch:myName"},
+			mockClient.EvalKeys,
+			"Keys not correct",
+		)
+		assert.Equal(t, []interface{}{"myName", "1", "eventStream", "events:3", "5-1"}, mockClient.EvalArgs, "Args not correct")
+	})
+
+	t.Run("Alive", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(0), nil),
+		}
+
+		err := RedisRepository{Client: mockClient}.SetCursorPosition(context.Background(), "myName", "eventStream", "5-1", false)
+
+		assert.Equal(t, domain.ErrCursorAlive, err, "Error not correct")
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{}
+
+		err := RedisRepository{Client: mockClient}.SetCursorPosition(context.Background(), "myName", "eventStream", "abc", false)
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Nil(t, mockClient.EvalKeys, "Script called with an invalid ID")
+	})
+}
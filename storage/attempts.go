@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+)
+
+//countAttemptScript records the start of processing the entry by the cursor.
+//The hash holds the entry being processed and how many times processing it was started.
+//Returns the number of earlier starts, 0 for an entry other than the stored one.
+const countAttemptScript = `
+local previous = 0
+if redis.call('HGET', KEYS[1], 'entry') == ARGV[1] then
+	previous = tonumber(redis.call('HGET', KEYS[1], 'count') or '0')
+end
+redis.call('HMSET', KEYS[1], 'entry', ARGV[1], 'count', previous + 1)
+return previous
+`
+
+//CountAttempt records the start of processing the entry of the stream by the named cursor.
+//The count is stored with the cursor and moves with it when it is taken over.
+//Returns how many times processing the entry was started before.
+func (r RedisRepository) CountAttempt(ctx context.Context, name, stream, ID string) (int, error) {
+	previous, err := r.client(ctx).Eval(
+		countAttemptScript,
+		[]string{r.attempts(name)},
+		stream+" "+ID,
+	).Int64()
+
+	if err != nil {
+		return 0, wrapError("CountAttempt", err)
+	}
+
+	return int(previous), nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountAttempt(t *testing.T) {
+	t.Run("Count attempt", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(2), nil),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		previous, err := storage.CountAttempt(context.Background(), "myName", "eventStream", "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 2, previous, "Earlier attempts not correct")
+		assert.Equal(t, countAttemptScript, mockClient.EvalScript, "Script not correct")
+		assert.Equal(t, []string{"attempts:myName"}, mockClient.EvalKeys, "Keys not correct")
+		assert.Equal(t, []interface{}{"eventStream 1-0"}, mockClient.EvalArgs, "Args not correct")
+	})
+
+	t.Run("Count attempt error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		_, err := storage.CountAttempt(context.Background(), "myName", "eventStream", "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
@@ -0,0 +1,98 @@
+package storage
+
+const (
+	//DefaultStream is the name of the entries stream when none is configured
+	DefaultStream string = "eventStream"
+	//DefaultDeadLetterStream is the name of the dead letter queue stream when none is configured
+	DefaultDeadLetterStream string = "faultyStream"
+
+	consumerSetKey       string = "consumers"
+	lastPositionKey      string = "lastPosition:"
+	heartKey             string = "heart:"
+	epochKey             string = "epoch:"
+	idempotencyKeyPrefix string = "idempotency:"
+	holdsKeyPrefix       string = "holds:"
+	attemptsKey          string = "attempts:"
+	namespaceSeparator   string = ":"
+)
+
+//Config holds the names of the keys used in Redis so independent pipelines can share one Redis.
+//Empty values fall back to the defaults.
+type Config struct {
+	//Stream is the name of the entries stream
+	Stream string
+	//DeadLetterStream is the name of the dead letter queue stream
+	DeadLetterStream string
+	//Namespace prefixes every key, stream names included
+	Namespace string
+
+	//Route is the name template of the stream entries are added to, see RouteObjectType.
+	//Entries are added to Stream when empty.
+	Route string
+	//Subscriptions are the names of the streams entries are read from, Stream when empty
+	Subscriptions []string
+}
+
+//streamName is the name of the default entries stream, without the namespace.
+func (r RedisRepository) streamName() string {
+	if r.Config.Stream == "" {
+		return DefaultStream
+	}
+
+	return r.Config.Stream
+}
+
+func (r RedisRepository) stream() string {
+	return r.streamKey(r.streamName())
+}
+
+//streamKey puts the name of the stream into the configured namespace.
+func (r RedisRepository) streamKey(name string) string {
+	return r.key(name)
+}
+
+func (r RedisRepository) deadLetterStream() string {
+	if r.Config.DeadLetterStream == "" {
+		return r.key(DefaultDeadLetterStream)
+	}
+
+	return r.key(r.Config.DeadLetterStream)
+}
+
+func (r RedisRepository) consumerSet() string {
+	return r.key(consumerSetKey)
+}
+
+func (r RedisRepository) lastPosition(ID string) string {
+	return r.key(lastPositionKey + ID)
+}
+
+func (r RedisRepository) heart(ID string) string {
+	return r.key(heartKey + ID)
+}
+
+func (r RedisRepository) epoch(ID string) string {
+	return r.key(epochKey + ID)
+}
+
+func (r RedisRepository) attempts(ID string) string {
+	return r.key(attemptsKey + ID)
+}
+
+func (r RedisRepository) idempotencyKey(key string) string {
+	return r.key(idempotencyKeyPrefix + key)
+}
+
+//holds is the hash of the retention holds on the stream.
+func (r RedisRepository) holds(stream string) string {
+	return r.key(holdsKeyPrefix + stream)
+}
+
+//key puts the name into the configured namespace.
+func (r RedisRepository) key(name string) string {
+	if r.Config.Namespace == "" {
+		return name
+	}
+
+	return r.Config.Namespace + namespaceSeparator + name
+}
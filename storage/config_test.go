@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("Default names", func(t *testing.T) {
+		r := RedisRepository{}
+
+		assert.Equal(t, "eventStream", r.stream(), "Stream name not correct")
+		assert.Equal(t, "faultyStream", r.deadLetterStream(), "Dead letter stream name not correct")
+		assert.Equal(t, "consumers", r.consumerSet(), "Consumer set name not correct")
+		assert.Equal(t, "heart:myName", r.heart("myName"), "Heart key not correct")
+	})
+
+	t.Run("Configured names", func(t *testing.T) {
+		r := RedisRepository{
+			Config: Config{
+				Stream:           "entries",
+				DeadLetterStream: "dlq",
+				Namespace:        "staging",
+			},
+		}
+
+		assert.Equal(t, "staging:entries", r.stream(), "Stream name not correct")
+		assert.Equal(t, "staging:dlq", r.deadLetterStream(), "Dead letter stream name not correct")
+		assert.Equal(t, "staging:consumers", r.consumerSet(), "Consumer set name not correct")
+		assert.Equal(t, "staging:lastPosition:myName", r.lastPosition("myName"), "Last position key not correct")
+		assert.Equal(t, "staging:heart:myName", r.heart("myName"), "Heart key not correct")
+		assert.Equal(t, "staging:epoch:myName", r.epoch("myName"), "Epoch key not correct")
+		assert.Equal(t, "staging:idempotency:key", r.idempotencyKey("key"), "Idempotency key not correct")
+	})
+
+	t.Run("Namespaced cursor patterns", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			SortReturnStringSliceCmd: redis.NewStringSliceResult(nil, redis.Nil),
+		}
+
+		var repo domain.EntryRepository = &RedisRepository{
+			Client: mockClient,
+			Config: Config{Namespace: "staging"},
+		}
+
+		_, err := repo.GetCursors(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "staging:consumers", mockClient.SortSet, "Sorted wrong set")
+		assert.Equal(t, "staging:lastPosition:*", mockClient.SortSort.By, "Sort pattern not correct")
+	})
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-redis/redis"
+)
+
+//DeadLetter stores the entry which failed processing into the dead letter queue.
+func (r RedisRepository) DeadLetter(ctx context.Context, e domain.Entry, reason string, attempts []domain.Attempt) error {
+	content, err := json.Marshal(e)
+
+	if err != nil {
+		return wrapError("DeadLetter", err)
+	}
+
+	record, err := json.Marshal(domain.DeadEntry{
+		OriginalID: e.ID,
+		Stream:     e.Stream,
+		Reason:     reason,
+		Attempts:   attempts,
+		Values:     map[string]string{entryField: string(content)},
+	})
+
+	if err != nil {
+		return wrapError("DeadLetter", err)
+	}
+
+	err = r.client(ctx).XAdd(&redis.XAddArgs{
+		Stream: r.deadLetterStream(),
+		Values: map[string]interface{}{deadEntryField: record},
+	}).Err()
+
+	if err != nil {
+		return wrapError("DeadLetter", err)
+	}
+
+	return nil
+}
+
+//GetDeadEntries fetches up to count entries from the dead letter queue starting with the start ID.
+func (r RedisRepository) GetDeadEntries(ctx context.Context, start string, count int64) ([]domain.DeadEntry, error) {
+	if start == "" {
+		start = "-"
+	}
+
+	messages, err := r.client(ctx).XRangeN(r.deadLetterStream(), start, "+", count).Result()
+
+	if err == redis.Nil {
+		return []domain.DeadEntry{}, nil
+	}
+
+	if err != nil {
+		return nil, wrapError("GetDeadEntries", err)
+	}
+
+	return parseDeadEntries(messages), nil
+}
+
+//GetDeadEntry fetches a single entry from the dead letter queue.
+//Returns domain.ErrNotFound if the entry does not exist.
+func (r RedisRepository) GetDeadEntry(ctx context.Context, ID string) (domain.DeadEntry, error) {
+	messages, err := r.client(ctx).XRangeN(r.deadLetterStream(), ID, ID, 1).Result()
+
+	if err != nil && err != redis.Nil {
+		return domain.DeadEntry{}, wrapError("GetDeadEntry", err)
+	}
+
+	entries := parseDeadEntries(messages)
+
+	if len(entries) == 0 {
+		return domain.DeadEntry{}, domain.ErrNotFound
+	}
+
+	return entries[0], nil
+}
+
+//ReplayDeadEntry adds the original message back to the stream and removes it from the dead letter queue.
+func (r RedisRepository) ReplayDeadEntry(ctx context.Context, ID string) error {
+	d, err := r.GetDeadEntry(ctx, ID)
+
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(d.Values))
+
+	for k, v := range d.Values {
+		values[k] = v
+	}
+
+	//Entries dead-lettered before routing was introduced came from the default stream.
+	stream := d.Stream
+
+	if stream == "" {
+		stream = r.streamName()
+	}
+
+	pipe := r.client(ctx).TxPipeline()
+
+	pipe.XAdd(&redis.XAddArgs{
+		Stream: r.streamKey(stream),
+		Values: values,
+	})
+	pipe.Process(redis.NewIntCmd("xdel", r.deadLetterStream(), ID))
+
+	_, err = pipe.Exec()
+
+	if err != nil {
+		return wrapError("ReplayDeadEntry", err)
+	}
+
+	return nil
+}
+
+//PurgeDeadEntries removes the entries from the dead letter queue.
+//Removes all the entries if no IDs are given.
+func (r RedisRepository) PurgeDeadEntries(ctx context.Context, IDs ...string) error {
+	var err error
+
+	if len(IDs) == 0 {
+		err = r.client(ctx).Del(r.deadLetterStream()).Err()
+	} else {
+		args := []interface{}{"xdel", r.deadLetterStream()}
+
+		for _, ID := range IDs {
+			args = append(args, ID)
+		}
+
+		err = r.client(ctx).Do(args...).Err()
+	}
+
+	if err != nil {
+		return wrapError("PurgeDeadEntries", err)
+	}
+
+	return nil
+}
+
+func parseDeadEntries(mm []redis.XMessage) []domain.DeadEntry {
+	results := make([]domain.DeadEntry, 0, len(mm))
+
+	for _, m := range mm {
+		record, ok := m.Values[deadEntryField].(string)
+
+		if !ok {
+			continue
+		}
+
+		var d domain.DeadEntry
+
+		err := json.Unmarshal([]byte(record), &d)
+
+		if err != nil {
+			continue
+		}
+
+		d.ID = m.ID
+		results = append(results, d)
+	}
+
+	return results
+}
+
+func stringValues(values map[string]interface{}) map[string]string {
+	results := make(map[string]string, len(values))
+
+	for k, v := range values {
+		results[k] = fmt.Sprint(v)
+	}
+
+	return results
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetter(t *testing.T) {
+	t.Run("Store dead entry", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAddReturnStringCmd: redis.NewStringResult("2-0", nil),
+		}
+
+		entry := domain.Entry{
+			ID:       "1-0",
+			ObjectID: 42,
+			Action:   "create",
+		}
+		attempts := []domain.Attempt{
+			domain.Attempt{Error: "some error"},
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.DeadLetter(context.Background(), entry, "some error", attempts)
+
+		require.Nil(t, err, "Error is not nil")
+		assert.Equal(t, DefaultDeadLetterStream, mockClient.XAddArgs.Stream, "Stream name not correct")
+
+		record, ok := mockClient.XAddArgs.Values[deadEntryField].([]byte)
+		require.True(t, ok, "Record not stored")
+
+		var d domain.DeadEntry
+		require.Nil(t, json.Unmarshal(record, &d), "Error unmarshaling record")
+
+		content, _ := json.Marshal(entry)
+
+		assert.Equal(t, "1-0", d.OriginalID, "Original ID not correct")
+		assert.Equal(t, "some error", d.Reason, "Reason not correct")
+		assert.Equal(t, attempts, d.Attempts, "Attempts not correct")
+		assert.Equal(t, map[string]string{entryField: string(content)}, d.Values, "Values not correct")
+	})
+
+	t.Run("Xadd error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAddReturnStringCmd: redis.NewStringResult("", errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.DeadLetter(context.Background(), domain.Entry{}, "some error", nil)
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestPurgeDeadEntries(t *testing.T) {
+	t.Run("Purge all entries", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DelReturnIntCmd: redis.NewIntResult(1, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.PurgeDeadEntries(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []string{DefaultDeadLetterStream}, mockClient.DelKeys, "Deleted wrong keys")
+		assert.Nil(t, mockClient.DoArgs, "XDEL was called")
+	})
+
+	t.Run("Purge some entries", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(int64(2), nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.PurgeDeadEntries(context.Background(), "1-0", "2-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []interface{}{"xdel", DefaultDeadLetterStream, "1-0", "2-0"}, mockClient.DoArgs, "XDEL args not correct")
+		assert.Nil(t, mockClient.DelKeys, "DEL was called")
+	})
+
+	t.Run("XDEL error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.PurgeDeadEntries(context.Background(), "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestGetDeadEntry(t *testing.T) {
+	t.Run("Entry does not exist", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.GetDeadEntry(context.Background(), "1-0")
+
+		assert.Equal(t, domain.ErrNotFound, err, "Error is not domain.ErrNotFound")
+		assert.Equal(t, DefaultDeadLetterStream, mockClient.XRangeNStream, "Stream name not correct")
+		assert.Equal(t, "1-0", mockClient.XRangeNStart, "Range start not correct")
+		assert.Equal(t, "1-0", mockClient.XRangeNStop, "Range stop not correct")
+	})
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/antekresic/grs/domain"
+)
+
+//unavailablePrefixes start the messages of errors returned when Redis cannot serve requests at the moment
+var unavailablePrefixes = []string{
+	"redis: connection pool timeout",
+	"redis: client is closed",
+	"LOADING ",
+	"MASTERDOWN ",
+	"CLUSTERDOWN ",
+	"TRYAGAIN ",
+	"READONLY ",
+}
+
+//wrapError adds the name of the method to the error.
+//Errors of a Redis which cannot be reached are marked with domain.ErrUnavailable.
+func wrapError(method string, err error) error {
+	if errors.Is(err, domain.ErrUnavailable) {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	if unavailable(err) {
+		return fmt.Errorf("%s: %w: %s", method, domain.ErrUnavailable, err)
+	}
+
+	return fmt.Errorf("%s: %s", method, err)
+}
+
+//unavailable reports whether the error comes from a Redis which cannot be reached or cannot serve requests.
+func unavailable(err error) bool {
+	var netErr net.Error
+
+	if errors.As(err, &netErr) || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	for _, prefix := range unavailablePrefixes {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapError(t *testing.T) {
+	t.Run("Unavailable Redis", func(t *testing.T) {
+		for _, err := range []error{
+			&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			io.EOF,
+			errors.New("redis: connection pool timeout"),
+			errors.New("LOADING Redis is loading the dataset in memory"),
+		} {
+			wrapped := wrapError("AddEntry", err)
+
+			assert.True(t, errors.Is(wrapped, domain.ErrUnavailable), "Error not marked as unavailable: %s", err)
+			assert.Contains(t, wrapped.Error(), err.Error(), "Original error lost")
+		}
+	})
+
+	t.Run("Wrapped twice", func(t *testing.T) {
+		err := wrapError("GetEntries", wrapError("parseEntries", io.EOF))
+
+		assert.True(t, errors.Is(err, domain.ErrUnavailable), "Error not marked as unavailable")
+	})
+
+	t.Run("Other errors", func(t *testing.T) {
+		err := wrapError("AddEntry", errors.New("ERR wrong number of arguments"))
+
+		assert.False(t, errors.Is(err, domain.ErrUnavailable), "Error marked as unavailable")
+		assert.EqualError(t, err, "AddEntry: ERR wrong number of arguments", "Message not correct")
+	})
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//cursorFields is the number of values fetched for every cursor in GetCursors
+	cursorFields int = 4
+)
+
+//storeCursorScript stores the cursor if its epoch is still the current one.
+//Cursors which were never taken over have no epoch key which counts as epoch 0.
+//Returns 1 if the cursor was stored, 0 if it was fenced out.
+const storeCursorScript = `
+local epoch = tonumber(redis.call('GET', KEYS[4]) or '0')
+if epoch ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[2])
+redis.call('SET', KEYS[2], ARGV[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[3], 1, 'PX', ARGV[4])
+else
+	redis.call('SET', KEYS[3], 1)
+end
+return 1
+`
+
+//refreshCursorScript refreshes the heart of the cursor if its epoch is still the current one
+//and it is still registered under the name.
+//Returns 1 if the heart was refreshed, 0 if the cursor was fenced out.
+const refreshCursorScript = `
+local epoch = tonumber(redis.call('GET', KEYS[3]) or '0')
+if epoch ~= tonumber(ARGV[1]) or redis.call('SISMEMBER', KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[2], 1, 'PX', ARGV[3])
+return 1
+`
+
+//RefreshCursor keeps the heart of the cursor alive for its heart timeout.
+//Returns a domain.FencedError if the epoch of the cursor was superseded by another consumer.
+func (r RedisRepository) RefreshCursor(ctx context.Context, cursor domain.StreamCursor) error {
+	refreshed, err := r.client(ctx).Eval(
+		refreshCursorScript,
+		[]string{r.consumerSet(), r.heart(cursor.Name), r.epoch(cursor.Name)},
+		cursor.Epoch,
+		cursor.Name,
+		milliseconds(time.Duration(cursor.HeartTimeout)),
+	).Int64()
+
+	if err != nil {
+		return wrapError("RefreshCursor", err)
+	}
+
+	if refreshed == 0 {
+		return &domain.FencedError{Name: cursor.Name, Epoch: cursor.Epoch}
+	}
+
+	return nil
+}
+
+//parseEpoch reads the epoch stored in Redis, missing epochs are epoch 0.
+func parseEpoch(value string) int64 {
+	e, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return e
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
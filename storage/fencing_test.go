@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreCursor(t *testing.T) {
+	t.Run("Current epoch", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(1), nil),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		err := storage.StoreCursor(context.Background(), domain.StreamCursor{
+			Name:         "myName",
+			Positions:    map[string]string{"eventStream": "1-0"},
+			HeartTimeout: int64(time.Second),
+			Epoch:        3,
+		})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			[]string{"consumers", "lastPosition:myName", "heart:myName", "epoch:myName"},
+			mockClient.EvalKeys,
+			"Keys not correct",
+		)
+		assert.Equal(t, []interface{}{int64(3), "myName", `{"eventStream":"1-0"}`, int64(1000)}, mockClient.EvalArgs, "Args not correct")
+	})
+
+	t.Run("Fenced out", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(0), nil),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		err := storage.StoreCursor(context.Background(), domain.StreamCursor{Name: "myName", Epoch: 3})
+
+		assert.True(t, domain.IsFenced(err), "Error is not a domain.FencedError")
+		assert.Equal(t, &domain.FencedError{Name: "myName", Epoch: 3}, err, "Error not correct")
+	})
+}
+
+func TestRefreshCursor(t *testing.T) {
+	t.Run("Current epoch", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(1), nil),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		err := storage.RefreshCursor(context.Background(), domain.StreamCursor{
+			Name:         "myName",
+			HeartTimeout: int64(time.Second),
+			Epoch:        3,
+		})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []string{"consumers", "heart:myName", "epoch:myName"}, mockClient.EvalKeys, "Keys not correct")
+		assert.Equal(t, []interface{}{int64(3), "myName", int64(1000)}, mockClient.EvalArgs, "Args not correct")
+	})
+
+	t.Run("Fenced out", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(0), nil),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		err := storage.RefreshCursor(context.Background(), domain.StreamCursor{Name: "myName"})
+
+		assert.True(t, domain.IsFenced(err), "Error is not a domain.FencedError")
+	})
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-redis/redis"
+)
+
+const (
+	groupStartID       string = "$"
+	busyGroupErrPrefix string = "BUSYGROUP"
+)
+
+//CreateGroup creates the consumer group on the stream, creating the stream if needed.
+//Creating a group which already exists is not considered an error.
+func (r RedisRepository) CreateGroup(ctx context.Context, group string) error {
+	err := r.client(ctx).Do("xgroup", "create", r.stream(), group, groupStartID, "mkstream").Err()
+
+	if err != nil && !strings.HasPrefix(err.Error(), busyGroupErrPrefix) {
+		return wrapError("CreateGroup", err)
+	}
+
+	return nil
+}
+
+//GetGroupEntries fetches entries from Redis Stream on behalf of a group consumer.
+//Use ">" as lastID to get new entries or an ID to get the consumers own pending entries.
+func (r RedisRepository) GetGroupEntries(ctx context.Context, group, consumer, lastID string) ([]domain.Entry, error) {
+	block, err := blockFor(ctx)
+
+	if err != nil {
+		return nil, wrapError("GetGroupEntries", err)
+	}
+
+	streams, err := r.client(ctx).XReadGroup(&redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{r.stream(), lastID},
+		Count:    readCount,
+		Block:    block,
+	}).Result()
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, wrapError("GetGroupEntries", err)
+	}
+
+	stream := getStreamByName(r.stream(), streams)
+
+	if stream == nil {
+		return nil, errors.New("GetGroupEntries: Stream not found")
+	}
+
+	return r.parseGroupEntries(ctx, group, stream.Messages), nil
+}
+
+//AckEntry acknowledges the entry as processed by the group.
+func (r RedisRepository) AckEntry(ctx context.Context, group, ID string) error {
+	err := r.client(ctx).XAck(r.stream(), group, ID).Err()
+
+	if err != nil {
+		return wrapError("AckEntry", err)
+	}
+
+	return nil
+}
+
+//GetPendingEntries fetches the entries which were delivered to group consumers but not acknowledged,
+//starting from the given ID or from the first one when the start is empty.
+func (r RedisRepository) GetPendingEntries(ctx context.Context, group, start string, count int64) ([]domain.PendingEntry, error) {
+	if start == "" {
+		start = rangeStart
+	}
+
+	pending, err := r.client(ctx).XPendingExt(&redis.XPendingExtArgs{
+		Stream: r.stream(),
+		Group:  group,
+		Start:  start,
+		End:    "+",
+		Count:  count,
+	}).Result()
+
+	if err == redis.Nil {
+		return []domain.PendingEntry{}, nil
+	}
+
+	if err != nil {
+		return nil, wrapError("GetPendingEntries", err)
+	}
+
+	results := make([]domain.PendingEntry, len(pending))
+
+	for i, p := range pending {
+		results[i] = domain.PendingEntry{
+			ID:         p.Id,
+			Consumer:   p.Consumer,
+			Idle:       p.Idle,
+			Deliveries: p.RetryCount,
+		}
+	}
+
+	return results, nil
+}
+
+//ClaimEntries transfers ownership of pending entries idle for at least minIdle to the consumer.
+func (r RedisRepository) ClaimEntries(ctx context.Context, group, consumer string, minIdle time.Duration, IDs []string) ([]domain.Entry, error) {
+	if len(IDs) == 0 {
+		return []domain.Entry{}, nil
+	}
+
+	messages, err := r.client(ctx).XClaim(&redis.XClaimArgs{
+		Stream:   r.stream(),
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: IDs,
+	}).Result()
+
+	if err == redis.Nil {
+		return []domain.Entry{}, nil
+	}
+
+	if err != nil {
+		return nil, wrapError("ClaimEntries", err)
+	}
+
+	return r.parseGroupEntries(ctx, group, messages), nil
+}
+
+//StoreHeart marks the group consumer as alive for the duration of the timeout.
+func (r RedisRepository) StoreHeart(ctx context.Context, consumer string, timeout time.Duration) error {
+	err := r.client(ctx).Set(r.heart(consumer), 1, timeout).Err()
+
+	if err != nil {
+		return wrapError("StoreHeart", err)
+	}
+
+	return nil
+}
+
+//HasHeart checks if the group consumer is still alive.
+func (r RedisRepository) HasHeart(ctx context.Context, consumer string) (bool, error) {
+	n, err := r.client(ctx).Exists(r.heart(consumer)).Result()
+
+	if err != nil {
+		return false, wrapError("HasHeart", err)
+	}
+
+	return n > 0, nil
+}
+
+//RemoveHeart marks the group consumer as dead so its pending entries can be reclaimed right away.
+func (r RedisRepository) RemoveHeart(ctx context.Context, consumer string) error {
+	err := r.client(ctx).Del(r.heart(consumer)).Err()
+
+	if err != nil {
+		return wrapError("RemoveHeart", err)
+	}
+
+	return nil
+}
+
+//parseGroupEntries parses the messages and acknowledges the faulty ones
+//so they don't stay pending in the group forever.
+func (r RedisRepository) parseGroupEntries(ctx context.Context, group string, mm []redis.XMessage) []domain.Entry {
+	entries, _ := r.parseEntries(ctx, r.streamName(), mm)
+
+	if len(entries) == len(mm) {
+		return entries
+	}
+
+	parsed := make(map[string]bool, len(entries))
+
+	for _, e := range entries {
+		parsed[e.ID] = true
+	}
+
+	for _, m := range mm {
+		if parsed[m.ID] {
+			continue
+		}
+
+		err := r.AckEntry(ctx, group, m.ID)
+
+		if err != nil {
+			log.Printf("Error acknowledging faulty entry %s: %s\n", m.ID, err)
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateGroup(t *testing.T) {
+	t.Run("Create new group", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult("OK", nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.CreateGroup(context.Background(), "myGroup")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			[]interface{}{"xgroup", "create", DefaultStream, "myGroup", groupStartID, "mkstream"},
+			mockClient.DoArgs,
+			"XGROUP CREATE args not correct",
+		)
+	})
+
+	t.Run("Group already exists", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(nil, errors.New("BUSYGROUP Consumer Group name already exists")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.CreateGroup(context.Background(), "myGroup")
+
+		assert.Nil(t, err, "Error is not nil")
+	})
+
+	t.Run("Some error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.CreateGroup(context.Background(), "myGroup")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestAckEntry(t *testing.T) {
+	t.Run("Ack entry", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAckReturnIntCmd: redis.NewIntResult(1, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.AckEntry(context.Background(), "myGroup", "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, DefaultStream, mockClient.XAckStream, "Stream name not correct")
+		assert.Equal(t, "myGroup", mockClient.XAckGroup, "Group not correct")
+		assert.Equal(t, []string{"1-0"}, mockClient.XAckIDs, "IDs not correct")
+	})
+
+	t.Run("XAck error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAckReturnIntCmd: redis.NewIntResult(0, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.AckEntry(context.Background(), "myGroup", "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestClaimEntries(t *testing.T) {
+	t.Run("No IDs to claim", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{}
+
+		storage := RedisRepository{Client: mockClient}
+
+		entries, err := storage.ClaimEntries(context.Background(), "myGroup", "me", time.Second, nil)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, entries, "Entries are not empty")
+		assert.Nil(t, mockClient.XClaimArgs, "XClaim was called")
+	})
+}
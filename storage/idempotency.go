@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-redis/redis"
+)
+
+const (
+	//DefaultIdempotencyTTL is how long idempotency keys are remembered when no TTL is configured
+	DefaultIdempotencyTTL time.Duration = 24 * time.Hour
+)
+
+//addOnceScript adds the entry to the stream unless the idempotency key is already recorded.
+//The stream is trimmed to about ARGV[4] entries unless it is 0.
+//Returns the entry ID and 1 if the entry was added or 0 if it was added before.
+const addOnceScript = `
+local id = redis.call('GET', KEYS[1])
+if id then
+	return {id, 0}
+end
+if tonumber(ARGV[4]) > 0 then
+	id = redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[4], '*', ARGV[1], ARGV[2])
+else
+	id = redis.call('XADD', KEYS[2], '*', ARGV[1], ARGV[2])
+end
+redis.call('SET', KEYS[1], id, 'PX', ARGV[3])
+return {id, 1}
+`
+
+//AddEntryOnce stores the entry into a Redis Stream unless an entry with the same
+//idempotency key was stored before. The key is recorded atomically with the entry.
+//Returns the ID assigned to the entry (the original one for duplicates) and if the entry was created.
+func (r RedisRepository) AddEntryOnce(ctx context.Context, e domain.Entry) (string, bool, error) {
+	if e.IdempotencyKey == "" {
+		ID, err := r.AddEntry(ctx, e)
+		return ID, err == nil, err
+	}
+
+	content, err := json.Marshal(e)
+
+	if err != nil {
+		return "", false, wrapError("AddEntryOnce", err)
+	}
+
+	result, err := r.client(ctx).Eval(addOnceScript, r.addOnceKeys(e), r.addOnceArgs(content)...).Result()
+
+	if err != nil {
+		return "", false, wrapError("AddEntryOnce", err)
+	}
+
+	ID, created, err := parseAddOnceResult(result)
+
+	if err != nil {
+		return "", false, wrapError("AddEntryOnce", err)
+	}
+
+	return ID, created, nil
+}
+
+//addOnce queues the idempotent add of the entry on the pipeline.
+func (r RedisRepository) addOnce(pipe redis.Pipeliner, e domain.Entry, content []byte) *redis.Cmd {
+	return pipe.Eval(addOnceScript, r.addOnceKeys(e), r.addOnceArgs(content)...)
+}
+
+func (r RedisRepository) addOnceKeys(e domain.Entry) []string {
+	return []string{r.idempotencyKey(e.IdempotencyKey), r.streamKey(r.route(e))}
+}
+
+func (r RedisRepository) addOnceArgs(content []byte) []interface{} {
+	return []interface{}{entryField, content, int64(r.idempotencyTTL() / time.Millisecond), r.maxLen()}
+}
+
+func (r RedisRepository) idempotencyTTL() time.Duration {
+	if r.IdempotencyTTL <= 0 {
+		return DefaultIdempotencyTTL
+	}
+
+	return r.IdempotencyTTL
+}
+
+func parseAddOnceResult(result interface{}) (string, bool, error) {
+	values, ok := result.([]interface{})
+
+	if !ok || len(values) != 2 {
+		return "", false, errors.New("unexpected script result")
+	}
+
+	ID, ok := values[0].(string)
+
+	if !ok {
+		return "", false, errors.New("unexpected script result")
+	}
+
+	created, _ := values[1].(int64)
+
+	return ID, created == 1, nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEntryOnce(t *testing.T) {
+	t.Run("Add new entry", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult([]interface{}{"1-0", int64(1)}, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient, IdempotencyTTL: time.Minute}
+
+		ID, created, err := storage.AddEntryOnce(context.Background(), domain.Entry{IdempotencyKey: "key"})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "1-0", ID, "ID not correct")
+		assert.True(t, created, "Entry not created")
+		assert.Equal(t, []string{"idempotency:key", DefaultStream}, mockClient.EvalKeys, "Keys not correct")
+		assert.Equal(t, int64(60000), mockClient.EvalArgs[2], "TTL not correct")
+		assert.Equal(t, int64(0), mockClient.EvalArgs[3], "Maximum length not correct")
+	})
+
+	t.Run("Duplicate entry", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult([]interface{}{"1-0", int64(0)}, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		ID, created, err := storage.AddEntryOnce(context.Background(), domain.Entry{IdempotencyKey: "key"})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "1-0", ID, "ID not correct")
+		assert.False(t, created, "Entry created")
+		assert.Equal(t, int64(DefaultIdempotencyTTL/time.Millisecond), mockClient.EvalArgs[2], "TTL not correct")
+	})
+
+	t.Run("No idempotency key", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAddReturnStringCmd: redis.NewStringResult("1-0", nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		ID, created, err := storage.AddEntryOnce(context.Background(), domain.Entry{})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "1-0", ID, "ID not correct")
+		assert.True(t, created, "Entry not created")
+		assert.Empty(t, mockClient.EvalScript, "Script was called")
+	})
+
+	t.Run("Eval error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, _, err := storage.AddEntryOnce(context.Background(), domain.Entry{IdempotencyKey: "key"})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
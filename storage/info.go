@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-redis/redis"
+)
+
+const (
+	noSuchKeyError string = "no such key"
+
+	//lagCountLimit is the maximum number of entries counted behind a cursor
+	lagCountLimit int64 = 10000
+	lagCountPage  int64 = 1000
+)
+
+//GetStreamInfo fetches the length and the last ID of the stream.
+//Streams which do not exist yet are reported as empty.
+func (r RedisRepository) GetStreamInfo(ctx context.Context, stream string) (domain.StreamInfo, error) {
+	info, err := r.streamInfo(ctx, stream, r.streamKey(stream))
+
+	if err != nil {
+		return domain.StreamInfo{}, wrapError("GetStreamInfo", err)
+	}
+
+	return info, nil
+}
+
+//GetDeadLetterInfo fetches the length and the last ID of the dead letter queue stream.
+func (r RedisRepository) GetDeadLetterInfo(ctx context.Context) (domain.StreamInfo, error) {
+	name := r.Config.DeadLetterStream
+
+	if name == "" {
+		name = DefaultDeadLetterStream
+	}
+
+	info, err := r.streamInfo(ctx, name, r.deadLetterStream())
+
+	if err != nil {
+		return domain.StreamInfo{}, wrapError("GetDeadLetterInfo", err)
+	}
+
+	return info, nil
+}
+
+func (r RedisRepository) streamInfo(ctx context.Context, name, key string) (domain.StreamInfo, error) {
+	info := domain.StreamInfo{Name: name}
+
+	//XInfo is not in the official version of the library so the command is built by hand.
+	reply, err := r.client(ctx).Do("xinfo", "stream", key).Result()
+
+	if err != nil && strings.Contains(err.Error(), noSuchKeyError) {
+		return info, nil
+	}
+
+	if err != nil {
+		return info, err
+	}
+
+	fields, ok := reply.([]interface{})
+
+	if !ok || len(fields)%2 != 0 {
+		return info, errors.New("unexpected XINFO reply")
+	}
+
+	for i := 0; i < len(fields); i += 2 {
+		field, _ := fields[i].(string)
+
+		switch field {
+		case "length":
+			info.Length, _ = fields[i+1].(int64)
+		case "last-generated-id":
+			info.LastID, _ = fields[i+1].(string)
+		}
+	}
+
+	return info, nil
+}
+
+//GetStreamHealth reports every subscribed stream together with the lag of the cursors reading it,
+//dead cursors waiting to be taken over included. Entries behind a cursor are counted up to lagCountLimit.
+func (r RedisRepository) GetStreamHealth(ctx context.Context) ([]domain.StreamHealth, error) {
+	cursors, err := r.GetCursors(ctx)
+
+	if err != nil {
+		return nil, wrapError("GetStreamHealth", err)
+	}
+
+	subscriptions := r.subscriptions()
+	health := make([]domain.StreamHealth, 0, len(subscriptions))
+
+	for _, stream := range subscriptions {
+		info, err := r.streamInfo(ctx, stream, r.streamKey(stream))
+
+		if err != nil {
+			return nil, wrapError("GetStreamHealth", err)
+		}
+
+		h := domain.StreamHealth{
+			Stream:    stream,
+			Length:    info.Length,
+			LastID:    info.LastID,
+			Consumers: []domain.ConsumerLag{},
+		}
+
+		for _, c := range cursors {
+			position, ok := c.Positions[stream]
+
+			if !ok {
+				continue
+			}
+
+			lag := domain.ConsumerLag{
+				Consumer:  c.Name,
+				Alive:     c.HasHeart,
+				Position:  position,
+				LagMillis: lagMillis(position, info.LastID),
+			}
+
+			lag.Entries, lag.EntriesTruncated, err = r.countAfter(ctx, r.streamKey(stream), position)
+
+			if err != nil {
+				return nil, wrapError("GetStreamHealth", err)
+			}
+
+			h.Consumers = append(h.Consumers, lag)
+		}
+
+		health = append(health, h)
+	}
+
+	return health, nil
+}
+
+//countAfter counts the entries of the stream after the ID, up to lagCountLimit.
+//Returns true if the limit was reached.
+func (r RedisRepository) countAfter(ctx context.Context, key, ID string) (int64, bool, error) {
+	var count int64
+	from := domain.NextID(ID)
+
+	for from != "" {
+		if count >= lagCountLimit {
+			return count, true, nil
+		}
+
+		messages, err := r.client(ctx).XRangeN(key, from, rangeEnd, lagCountPage).Result()
+
+		if err != nil && err != redis.Nil {
+			return 0, false, err
+		}
+
+		count += int64(len(messages))
+
+		if int64(len(messages)) < lagCountPage {
+			break
+		}
+
+		from = domain.NextID(messages[len(messages)-1].ID)
+	}
+
+	return count, false, nil
+}
+
+//lagMillis returns the time in milliseconds between adding the entries with the given IDs, 0 if the first one is not older.
+func lagMillis(from, to string) int64 {
+	fromMillis, _, err := domain.ParseID(from)
+
+	if err != nil {
+		return 0
+	}
+
+	toMillis, _, err := domain.ParseID(to)
+
+	if err != nil || toMillis <= fromMillis {
+		return 0
+	}
+
+	return int64(toMillis - fromMillis)
+}
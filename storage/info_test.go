@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStreamInfo(t *testing.T) {
+	t.Run("Parse XINFO reply", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult([]interface{}{
+				"length", int64(12),
+				"radix-tree-keys", int64(1),
+				"last-generated-id", "5-1",
+				"first-entry", []interface{}{"1-0", []interface{}{"entry", "{}"}},
+			}, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient, Config: Config{Namespace: "ns"}}
+
+		info, err := storage.GetStreamInfo(context.Background(), "events")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "events", info.Name, "Name not correct")
+		assert.Equal(t, int64(12), info.Length, "Length not correct")
+		assert.Equal(t, "5-1", info.LastID, "Last ID not correct")
+		assert.Equal(t, []interface{}{"xinfo", "stream", "ns:events"}, mockClient.DoArgs, "XINFO args not correct")
+	})
+
+	t.Run("Stream does not exist", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(nil, errors.New("ERR no such key")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		info, err := storage.GetDeadLetterInfo(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, DefaultDeadLetterStream, info.Name, "Name not correct")
+		assert.Equal(t, int64(0), info.Length, "Length not correct")
+		assert.Equal(t, []interface{}{"xinfo", "stream", DefaultDeadLetterStream}, mockClient.DoArgs, "XINFO args not correct")
+	})
+
+	t.Run("Some error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			DoReturnCmd: redis.NewCmdResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.GetStreamInfo(context.Background(), DefaultStream)
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestGetStreamHealth(t *testing.T) {
+	t.Run("Report lag of live and dead cursors", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			SortReturnStringSliceCmd: redis.NewStringSliceResult([]string{
+				"1", "alive", "1000-0", "",
+				"", "dead", `{"eventStream":"4000-2","events:3":"5-0"}`, "2",
+			}, nil),
+			DoReturnCmd: redis.NewCmdResult([]interface{}{
+				"length", int64(12),
+				"last-generated-id", "5000-1",
+			}, nil),
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		health, err := storage.GetStreamHealth(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, []domain.StreamHealth{
+			domain.StreamHealth{
+				Stream: DefaultStream,
+				Length: 12,
+				LastID: "5000-1",
+				Consumers: []domain.ConsumerLag{
+					domain.ConsumerLag{Consumer: "alive", Alive: true, Position: "1000-0", LagMillis: 4000},
+					domain.ConsumerLag{Consumer: "dead", Position: "4000-2", LagMillis: 1000},
+				},
+			},
+		}, health, "Health not correct")
+		assert.Equal(t, "4000-3", mockClient.XRangeNStart, "Entries not counted after the position")
+		assert.Equal(t, lagCountPage, mockClient.XRangeNCount, "Page size not correct")
+	})
+
+	t.Run("Cursors error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			SortReturnStringSliceCmd: redis.NewStringSliceResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.GetStreamHealth(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
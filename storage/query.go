@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/go-redis/redis"
+)
+
+const (
+	rangeStart string = "-"
+	rangeEnd   string = "+"
+
+	//streamStartID is lower than the ID of any entry
+	streamStartID string = "0-0"
+
+	//maxScanFactor limits how many messages are scanned for a single page
+	//when the filters match only a few of them.
+	maxScanFactor int64 = 10
+)
+
+//QueryEntries fetches a page of entries matching the query from the Redis Stream.
+//Malformed messages are skipped.
+func (r RedisRepository) QueryEntries(ctx context.Context, q domain.EntryQuery) (domain.EntryPage, error) {
+	page := domain.EntryPage{
+		Entries: []domain.Entry{},
+	}
+
+	if q.Count <= 0 {
+		return page, nil
+	}
+
+	name, stream := r.queryStream(q)
+	from, to := q.From, q.To
+
+	if from == "" {
+		from = rangeStart
+	}
+
+	if to == "" {
+		to = rangeEnd
+	}
+
+	for scanned := int64(0); scanned < q.Count*maxScanFactor; {
+		var messages []redis.XMessage
+		var err error
+
+		if q.Reverse {
+			messages, err = r.client(ctx).XRevRangeN(stream, to, from, q.Count).Result()
+		} else {
+			messages, err = r.client(ctx).XRangeN(stream, from, to, q.Count).Result()
+		}
+
+		if err != nil && err != redis.Nil {
+			return domain.EntryPage{}, wrapError("QueryEntries", err)
+		}
+
+		var last string
+
+		for _, m := range messages {
+			scanned++
+			last = m.ID
+
+			e, err := parseEntry(m)
+
+			if err == nil && matchesQuery(q, e) {
+				e.Stream = name
+				page.Entries = append(page.Entries, e)
+			}
+
+			if int64(len(page.Entries)) == q.Count {
+				break
+			}
+		}
+
+		//Reached the end of the range without filling the page.
+		if last == "" || (int64(len(messages)) < q.Count && int64(len(page.Entries)) < q.Count) {
+			return page, nil
+		}
+
+		if q.Reverse {
+			to = domain.PreviousID(last)
+			page.Next = to
+		} else {
+			from = domain.NextID(last)
+			page.Next = from
+		}
+
+		if int64(len(page.Entries)) == q.Count || page.Next == "" {
+			return page, nil
+		}
+	}
+
+	//Scan limit reached, the client continues from the next ID.
+	return page, nil
+}
+
+//TailEntries waits for entries added to the Redis Stream after the query From ID
+//and returns the ones matching the query filters. Malformed messages are skipped.
+//An empty From ID waits for entries added after the newest one in the stream.
+//Next is the ID of the last message read, or the From ID if there were none.
+func (r RedisRepository) TailEntries(ctx context.Context, q domain.EntryQuery) (domain.EntryPage, error) {
+	name, key := r.queryStream(q)
+
+	if q.From == "" {
+		newest, err := r.newestID(ctx, key)
+
+		if err != nil {
+			return domain.EntryPage{}, wrapError("TailEntries", err)
+		}
+
+		q.From = newest
+	}
+
+	page := domain.EntryPage{
+		Entries: []domain.Entry{},
+		Next:    q.From,
+	}
+
+	block, err := blockFor(ctx)
+
+	if err != nil {
+		return domain.EntryPage{}, wrapError("TailEntries", err)
+	}
+
+	streams, err := r.client(ctx).XRead(&redis.XReadArgs{
+		Streams: []string{key, q.From},
+		Count:   q.Count,
+		Block:   block,
+	}).Result()
+
+	if err == redis.Nil {
+		return page, nil
+	}
+
+	if err != nil {
+		return domain.EntryPage{}, wrapError("TailEntries", err)
+	}
+
+	stream := getStreamByName(key, streams)
+
+	if stream == nil {
+		return page, nil
+	}
+
+	for _, m := range stream.Messages {
+		page.Next = m.ID
+
+		e, err := parseEntry(m)
+
+		if err == nil && matchesQuery(q, e) {
+			e.Stream = name
+			page.Entries = append(page.Entries, e)
+		}
+	}
+
+	return page, nil
+}
+
+func matchesQuery(q domain.EntryQuery, e domain.Entry) bool {
+	if q.ObjectID != 0 && q.ObjectID != e.ObjectID {
+		return false
+	}
+
+	if q.ObjectType != 0 && q.ObjectType != e.ObjectType {
+		return false
+	}
+
+	if q.Action != "" && q.Action != e.Action {
+		return false
+	}
+
+	return true
+}
+
+//newestID returns the ID of the newest entry in the stream, streamStartID if it is empty.
+func (r RedisRepository) newestID(ctx context.Context, key string) (string, error) {
+	newest, err := r.client(ctx).XRevRangeN(key, rangeEnd, rangeStart, 1).Result()
+
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	if len(newest) == 0 {
+		return streamStartID, nil
+	}
+
+	return newest[0].ID, nil
+}
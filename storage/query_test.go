@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryEntries(t *testing.T) {
+	t.Run("Empty stream", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		page, err := storage.QueryEntries(context.Background(), domain.EntryQuery{Count: 10})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, page.Entries, "Entries are not empty")
+		assert.Empty(t, page.Next, "Next is not empty")
+		assert.Equal(t, rangeStart, mockClient.XRangeNStart, "Range start not correct")
+		assert.Equal(t, rangeEnd, mockClient.XRangeNStop, "Range stop not correct")
+		assert.Equal(t, int64(10), mockClient.XRangeNCount, "Range count not correct")
+	})
+
+	t.Run("Reverse range", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRevRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.QueryEntries(context.Background(), domain.EntryQuery{From: "1-0", To: "2-0", Count: 10, Reverse: true})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "2-0", mockClient.XRevRangeNStart, "Range start not correct")
+		assert.Equal(t, "1-0", mockClient.XRevRangeNStop, "Range stop not correct")
+	})
+}
+
+func TestMatchesQuery(t *testing.T) {
+	entry := domain.Entry{ObjectID: 1, ObjectType: 2, Action: "create"}
+
+	assert.True(t, matchesQuery(domain.EntryQuery{}, entry), "Empty query does not match")
+	assert.True(t, matchesQuery(domain.EntryQuery{ObjectType: 2, Action: "create"}, entry), "Query does not match")
+	assert.False(t, matchesQuery(domain.EntryQuery{ObjectID: 3}, entry), "Query on object ID matches")
+	assert.False(t, matchesQuery(domain.EntryQuery{Action: "delete"}, entry), "Query on action matches")
+}
+
+func TestTailEntries(t *testing.T) {
+	t.Run("Start after the newest entry of the routed stream", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRevRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+			XReadReturnXStreamSliceCmd:       redis.NewXStreamSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient, Config: Config{Route: "events:" + RouteObjectType}}
+
+		page, err := storage.TailEntries(context.Background(), domain.EntryQuery{ObjectType: 3, Count: 10})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "events:3", mockClient.XRevRangeNStream, "Newest entry not read from the routed stream")
+		assert.Equal(t, []string{"events:3", "0-0"}, mockClient.XReadArgs.Streams, "Did not tail the routed stream")
+		assert.Equal(t, "0-0", page.Next, "Next not correct")
+	})
+}
@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"reflect"
 	"time"
 
 	"github.com/antekresic/grs/domain"
@@ -12,14 +14,10 @@ import (
 )
 
 const (
-	streamName       string        = "eventStream"
-	consumerSet      string        = "consumers"
-	lastPositionKey  string        = "lastPosition:"
-	heartKey         string        = "heart:"
-	entryField       string        = "entry"
-	readCount        int64         = 10
-	readBlock        time.Duration = 1 * time.Second
-	faultyStreamName string        = "faultyStream"
+	entryField     string        = "entry"
+	readCount      int64         = 10
+	readBlock      time.Duration = 1 * time.Second
+	deadEntryField string        = "record"
 )
 
 //RedisClient is an interface to the 3rd party Redis client.
@@ -27,136 +25,358 @@ type RedisClient interface {
 	XAdd(*redis.XAddArgs) *redis.StringCmd
 	XRead(*redis.XReadArgs) *redis.XStreamSliceCmd
 	TxPipeline() redis.Pipeliner
+	Pipeline() redis.Pipeliner
 	Sort(set string, sort *redis.Sort) *redis.StringSliceCmd
 	Watch(fn func(*redis.Tx) error, keys ...string) error
+	XReadGroup(*redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAck(stream, group string, ids ...string) *redis.IntCmd
+	XPendingExt(*redis.XPendingExtArgs) *redis.XPendingExtCmd
+	XClaim(*redis.XClaimArgs) *redis.XMessageSliceCmd
+	Do(args ...interface{}) *redis.Cmd
+	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Exists(keys ...string) *redis.IntCmd
+	XRangeN(stream, start, stop string, count int64) *redis.XMessageSliceCmd
+	XRevRangeN(stream, start, stop string, count int64) *redis.XMessageSliceCmd
+	Del(keys ...string) *redis.IntCmd
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	HSet(key, field string, value interface{}) *redis.BoolCmd
+	HGetAll(key string) *redis.StringStringMapCmd
 }
 
 //RedisRepository is a Redis implementation of EntryRepository.
 type RedisRepository struct {
 	Client RedisClient
 
+	//Config holds the names of the keys used in Redis
+	Config Config
+
+	//IdempotencyTTL is how long idempotency keys are remembered
+	IdempotencyTTL time.Duration
+
+	//MaxLen is the approximate number of entries a stream is trimmed to on every add,
+	//streams are not trimmed on add when 0
+	MaxLen int64
+
 	name   string
 	lastID string
 }
 
+//contextClient is implemented by clients which can be bound to a context, like *redis.Client.
+type contextClient interface {
+	WithContext(ctx context.Context) *redis.Client
+}
+
+//client returns the Redis client bound to the context when the client supports it.
+func (r RedisRepository) client(ctx context.Context) RedisClient {
+	if c, ok := r.Client.(contextClient); ok {
+		return c.WithContext(ctx)
+	}
+
+	return r.Client
+}
+
 //AddEntry stores entry into a Redis Stream.
-func (r RedisRepository) AddEntry(e domain.Entry) error {
+//Returns the ID assigned to the entry.
+func (r RedisRepository) AddEntry(ctx context.Context, e domain.Entry) (string, error) {
 
 	content, err := json.Marshal(e)
 
 	if err != nil {
-		return fmt.Errorf("AddEntry: %s", err)
+		return "", wrapError("AddEntry", err)
 	}
 
 	m := map[string]interface{}{entryField: content}
 
-	err = r.Client.XAdd(&redis.XAddArgs{
-		Stream: streamName,
-		Values: m,
-	}).Err()
+	ID, err := r.client(ctx).XAdd(&redis.XAddArgs{
+		Stream:       r.streamKey(r.route(e)),
+		MaxLenApprox: r.maxLen(),
+		Values:       m,
+	}).Result()
 
 	if err != nil {
-		return fmt.Errorf("AddEntry: %s", err)
+		return "", wrapError("AddEntry", err)
 	}
 
-	return nil
+	return ID, nil
+}
+
+//GetEntry fetches a single entry from the subscribed Redis Streams.
+//Returns domain.ErrNotFound if the entry does not exist.
+func (r RedisRepository) GetEntry(ctx context.Context, ID string) (domain.Entry, error) {
+	for _, stream := range r.subscriptions() {
+		messages, err := r.client(ctx).XRangeN(r.streamKey(stream), ID, ID, 1).Result()
+
+		if err != nil && err != redis.Nil {
+			return domain.Entry{}, wrapError("GetEntry", err)
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		entry, err := parseEntry(messages[0])
+
+		if err != nil {
+			return domain.Entry{}, wrapError("GetEntry", err)
+		}
+
+		entry.Stream = stream
+		return entry, nil
+	}
+
+	return domain.Entry{}, domain.ErrNotFound
+}
+
+//AddEntries stores all the entries into a Redis Stream in a single round trip.
+//Entries with an idempotency key which was already used are not stored again.
+//Returns the outcome of every entry in the same order, entries fail one by one.
+//The error of the first failed entry is also returned if none of them were added.
+func (r RedisRepository) AddEntries(ctx context.Context, entries []domain.Entry) ([]domain.AddResult, error) {
+	results := make([]domain.AddResult, len(entries))
+
+	if len(entries) == 0 {
+		return results, nil
+	}
+
+	pipe := r.client(ctx).Pipeline()
+	cmds := make([]redis.Cmder, len(entries))
+
+	for i, e := range entries {
+		content, err := json.Marshal(e)
+
+		if err != nil {
+			results[i].Err = wrapError("AddEntries", err)
+			continue
+		}
+
+		if e.IdempotencyKey != "" {
+			cmds[i] = r.addOnce(pipe, e, content)
+			continue
+		}
+
+		cmds[i] = pipe.XAdd(&redis.XAddArgs{
+			Stream:       r.streamKey(r.route(e)),
+			MaxLenApprox: r.maxLen(),
+			Values:       map[string]interface{}{entryField: content},
+		})
+	}
+
+	//Exec returns the first failed command only, every command carries its own error.
+	pipe.Exec()
+
+	var firstErr error
+	added := false
+
+	for i, cmd := range cmds {
+		if cmd != nil {
+			results[i] = addResult(cmd)
+		}
+
+		if results[i].Err == nil {
+			added = true
+		} else if firstErr == nil {
+			firstErr = results[i].Err
+		}
+	}
+
+	if !added {
+		return results, firstErr
+	}
+
+	return results, nil
 }
 
-//StoreCursor saves the data necessary to keep track of the streamers last position
-func (r RedisRepository) StoreCursor(cursor domain.StreamCursor) error {
-	pipe := r.Client.TxPipeline()
+//addResult reads the outcome of an add queued on the pipeline.
+func addResult(cmd redis.Cmder) domain.AddResult {
+	if cmd.Err() != nil {
+		return domain.AddResult{Err: wrapError("AddEntries", cmd.Err())}
+	}
+
+	c, ok := cmd.(*redis.Cmd)
+
+	if !ok {
+		return domain.AddResult{ID: cmd.(*redis.StringCmd).Val(), Created: true}
+	}
+
+	ID, created, err := parseAddOnceResult(c.Val())
 
-	pipe.SAdd(consumerSet, cursor.Name)
-	pipe.Set(lastPosition(cursor.Name), cursor.LastID, time.Duration(0))
-	pipe.Set(heart(cursor.Name), 1, time.Duration(cursor.HeartTimeout))
+	if err != nil {
+		return domain.AddResult{Err: wrapError("AddEntries", err)}
+	}
+
+	return domain.AddResult{ID: ID, Created: created}
+}
 
-	_, err := pipe.Exec()
+//StoreCursor saves the data necessary to keep track of the streamers last position.
+//Returns a domain.FencedError if the epoch of the cursor was superseded by another consumer.
+func (r RedisRepository) StoreCursor(ctx context.Context, cursor domain.StreamCursor) error {
+	stored, err := r.client(ctx).Eval(
+		storeCursorScript,
+		[]string{r.consumerSet(), r.lastPosition(cursor.Name), r.heart(cursor.Name), r.epoch(cursor.Name)},
+		cursor.Epoch,
+		cursor.Name,
+		encodePositions(cursor.Positions),
+		milliseconds(time.Duration(cursor.HeartTimeout)),
+	).Int64()
 
 	if err != nil {
-		return fmt.Errorf("StoreCursor: %s", err)
+		return wrapError("StoreCursor", err)
+	}
+
+	if stored == 0 {
+		return &domain.FencedError{Name: cursor.Name, Epoch: cursor.Epoch}
 	}
 
 	return nil
 }
 
-//GetEntries fetches events from Redis Stream.
-func (r *RedisRepository) GetEntries(lastID string) (entries []domain.Entry, newLastID string, err error) {
-	streams, err := r.Client.XRead(&redis.XReadArgs{
-		Streams: []string{streamName, lastID},
+//GetEntries fetches events from all the subscribed Redis Streams in a single read.
+//Positions map the stream names to the IDs to read after, streams without a position
+//are read from new entries only. Returns the positions to continue from.
+//Streams without a position get the ID of their newest entry, so entries added to them
+//while other streams are processed are not skipped by the next read.
+func (r *RedisRepository) GetEntries(ctx context.Context, positions map[string]string) (entries []domain.Entry, newPositions map[string]string, err error) {
+	block, err := blockFor(ctx)
+
+	if err != nil {
+		return nil, positions, wrapError("GetEntries", err)
+	}
+
+	subscriptions := r.subscriptions()
+	newPositions = make(map[string]string, len(subscriptions))
+
+	for _, stream := range subscriptions {
+		ID, ok := positions[stream]
+
+		if !ok {
+			ID, err = r.newestID(ctx, r.streamKey(stream))
+
+			if err != nil {
+				return nil, positions, wrapError("GetEntries", err)
+			}
+		}
+
+		newPositions[stream] = ID
+	}
+
+	//XREAD takes all the stream names first, then all the IDs.
+	args := make([]string, len(subscriptions)*2)
+
+	for i, stream := range subscriptions {
+		args[i] = r.streamKey(stream)
+		args[len(subscriptions)+i] = newPositions[stream]
+	}
+
+	streams, err := r.client(ctx).XRead(&redis.XReadArgs{
+		Streams: args,
 		Count:   readCount,
-		Block:   readBlock,
+		Block:   block,
 	}).Result()
 
 	if err == redis.Nil {
-		return nil, "", nil
+		return nil, newPositions, nil
 	}
 
 	if err != nil {
-		return nil, "", fmt.Errorf("GetEntries: %s", err)
+		return nil, positions, wrapError("GetEntries", err)
 	}
 
-	stream := getStreamByName(streamName, streams)
+	for _, stream := range subscriptions {
+		s := getStreamByName(r.streamKey(stream), streams)
 
-	if stream == nil {
-		return nil, "", errors.New("GetEntries: Stream not found")
-	}
+		if s == nil || len(s.Messages) == 0 {
+			continue
+		}
 
-	entries, newLastID = r.parseEntries(stream.Messages)
+		parsed, lastID := r.parseEntries(ctx, stream, s.Messages)
+		entries = append(entries, parsed...)
+		newPositions[stream] = lastID
+	}
 
-	return entries, newLastID, nil
+	return entries, newPositions, nil
 }
 
-func (r RedisRepository) parseEntries(mm []redis.XMessage) ([]domain.Entry, string) {
+func (r RedisRepository) parseEntries(ctx context.Context, stream string, mm []redis.XMessage) ([]domain.Entry, string) {
 	results := make([]domain.Entry, 0, len(mm))
-	tmpEntry := domain.Entry{}
 	var lastID string
 
 	for _, m := range mm {
 		lastID = m.ID
-		entry, ok := m.Values[entryField]
+		entry, err := parseEntry(m)
 
-		if !ok {
-			log.Printf("Failed getting entry from XMessage for ID: %s", m.ID)
-			r.handleFaultyEntry(m.ID, m.Values)
+		if err != nil {
+			log.Printf("Failed parsing entry from XMessage for ID %s: %s", m.ID, err)
+			r.handleFaultyEntry(ctx, stream, m.ID, m.Values, err.Error())
 			continue
 		}
 
-		entryString, ok := entry.(string)
+		entry.Stream = stream
+		results = append(results, entry)
+	}
 
-		if !ok {
-			log.Printf("Failed converting entry to string from XMessage for ID: %s", m.ID)
-			r.handleFaultyEntry(m.ID, m.Values)
-			continue
-		}
+	return results, lastID
+}
 
-		err := json.Unmarshal([]byte(entryString), &tmpEntry)
+//parseEntry decodes the entry stored in the stream message.
+func parseEntry(m redis.XMessage) (domain.Entry, error) {
+	var e domain.Entry
+	entry, ok := m.Values[entryField]
 
-		if err != nil {
-			log.Printf("Failed unmarshaling entry from XMessage for ID: %s", m.ID)
-			r.handleFaultyEntry(m.ID, m.Values)
-			continue
-		}
+	if !ok {
+		return e, errors.New("missing entry field")
+	}
 
-		tmpEntry.ID = m.ID
+	entryString, ok := entry.(string)
 
-		results = append(results, tmpEntry)
+	if !ok {
+		return e, errors.New("entry field is not a string")
 	}
 
-	return results, lastID
-}
+	err := json.Unmarshal([]byte(entryString), &e)
+
+	if err != nil {
+		return e, fmt.Errorf("malformed entry: %s", err)
+	}
 
-func (r RedisRepository) handleFaultyEntry(ID string, values map[string]interface{}) {
-	pipe := r.Client.TxPipeline()
+	e.ID = m.ID
 
-	//XDel is not in an official version of the library.
-	//There is a PR merged in master that adds the support for this command.
-	//pipe.XDel(streamName, ID)
-	pipe.XAdd(&redis.XAddArgs{
-		Stream: faultyStreamName,
-		Values: values,
+	return e, nil
+}
+
+//moveFaultyEntryScript deletes the malformed message and adds its record to the dead letter queue.
+//The record is added only if the message was still in the stream, so consumers reading the same message
+//concurrently dead-letter it once.
+//Returns 1 if the message was moved, 0 if another consumer moved it already.
+const moveFaultyEntryScript = `
+if redis.call('XDEL', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('XADD', KEYS[2], '*', ARGV[2], ARGV[3])
+return 1
+`
+
+//handleFaultyEntry moves the malformed message from the stream to the dead letter queue.
+func (r RedisRepository) handleFaultyEntry(ctx context.Context, stream, ID string, values map[string]interface{}, reason string) {
+	record, err := json.Marshal(domain.DeadEntry{
+		OriginalID: ID,
+		Stream:     stream,
+		Reason:     reason,
+		Attempts:   []domain.Attempt{},
+		Values:     stringValues(values),
 	})
 
-	_, err := pipe.Exec()
+	if err != nil {
+		log.Printf("Error handling faulty entry: %s\n", err)
+		return
+	}
+
+	err = r.client(ctx).Eval(
+		moveFaultyEntryScript,
+		[]string{r.streamKey(stream), r.deadLetterStream()},
+		ID,
+		deadEntryField,
+		string(record),
+	).Err()
 
 	if err != nil {
 		log.Printf("Error handling faulty entry: %s\n", err)
@@ -174,13 +394,14 @@ func getStreamByName(name string, ss []redis.XStream) *redis.XStream {
 }
 
 //GetCursors fetches all the information about cursors from Redis.
-func (r RedisRepository) GetCursors() (cursors []domain.StreamCursor, err error) {
-	results, err := r.Client.Sort(consumerSet, &redis.Sort{
-		By: lastPosition("*"),
+func (r RedisRepository) GetCursors(ctx context.Context) (cursors []domain.StreamCursor, err error) {
+	results, err := r.client(ctx).Sort(r.consumerSet(), &redis.Sort{
+		By: r.lastPosition("*"),
 		Get: []string{
-			heart("*"),
+			r.heart("*"),
 			"#",
-			lastPosition("*"),
+			r.lastPosition("*"),
+			r.epoch("*"),
 		},
 		Alpha: true,
 	}).Result()
@@ -190,22 +411,23 @@ func (r RedisRepository) GetCursors() (cursors []domain.StreamCursor, err error)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("GetCursors: %s", err)
+		return nil, wrapError("GetCursors", err)
 	}
 
-	if len(results) < 3 {
+	if len(results) < cursorFields {
 		return []domain.StreamCursor{}, nil
 	}
 
-	cursors = make([]domain.StreamCursor, len(results)/3)
+	cursors = make([]domain.StreamCursor, len(results)/cursorFields)
 
 	i := 0
 
-	for len(results) >= 3 {
+	for len(results) >= cursorFields {
 		cursors[i].HasHeart = results[0] != ""
 		cursors[i].Name = results[1]
-		cursors[i].LastID = results[2]
-		results = results[3:]
+		cursors[i].Positions = r.parsePositions(results[2])
+		cursors[i].Epoch = parseEpoch(results[3])
+		results = results[cursorFields:]
 		i++
 	}
 
@@ -213,38 +435,112 @@ func (r RedisRepository) GetCursors() (cursors []domain.StreamCursor, err error)
 }
 
 //StealCursor trys to get the identity and last position from an existing consumer.
+//The new cursor gets the epoch of the old one incremented by one and the old name is fenced out.
 //Returns redis.TxFailedErr if transaction fails which means that the consumer is alive.
-func (r RedisRepository) StealCursor(oldCursor domain.StreamCursor, newConsumerName string) error {
-	return r.Client.Watch(func(tx *redis.Tx) error {
-		lastPositionID, err := tx.Get(lastPosition(oldCursor.Name)).Result()
+func (r RedisRepository) StealCursor(ctx context.Context, oldCursor domain.StreamCursor, newConsumerName string) error {
+	return r.client(ctx).Watch(func(tx *redis.Tx) error {
+		lastPositionID, err := tx.Get(r.lastPosition(oldCursor.Name)).Result()
 		if err != nil {
-			return fmt.Errorf("StealCursor: %s", err)
+			return wrapError("StealCursor", err)
 		}
 
 		//If last position changed, fail the transaction.
-		if lastPositionID != oldCursor.LastID {
+		if !reflect.DeepEqual(r.parsePositions(lastPositionID), oldCursor.Positions) {
 			return redis.TxFailedErr
 		}
 
+		//If the consumer came back to life in the meantime, fail the transaction.
+		alive, err := tx.Exists(r.heart(oldCursor.Name)).Result()
+		if err != nil {
+			return wrapError("StealCursor", err)
+		}
+
+		if alive > 0 {
+			return redis.TxFailedErr
+		}
+
+		//If the cursor was taken over by someone else in the meantime, fail the transaction.
+		currentEpoch, err := tx.Get(r.epoch(oldCursor.Name)).Result()
+		if err != nil && err != redis.Nil {
+			return wrapError("StealCursor", err)
+		}
+
+		if parseEpoch(currentEpoch) != oldCursor.Epoch {
+			return redis.TxFailedErr
+		}
+
+		//The attempts at the entry being processed move with the cursor.
+		attempts, err := tx.HGetAll(r.attempts(oldCursor.Name)).Result()
+		if err != nil {
+			return wrapError("StealCursor", err)
+		}
+
+		newEpoch := oldCursor.Epoch + 1
+
 		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
-			pipe.SRem(consumerSet, oldCursor.Name)
-			pipe.Del(lastPosition(oldCursor.Name))
-			pipe.SAdd(consumerSet, newConsumerName)
-			pipe.Set(lastPosition(newConsumerName), lastPositionID, time.Duration(0))
-			pipe.Set(heart(newConsumerName), 1, time.Duration(oldCursor.HeartTimeout))
+			pipe.SRem(r.consumerSet(), oldCursor.Name)
+			pipe.Del(r.lastPosition(oldCursor.Name))
+			//The epoch of the old name is kept for good, once it expired the previous owner would pass as epoch 0.
+			pipe.Set(r.epoch(oldCursor.Name), newEpoch, time.Duration(0))
+			pipe.SAdd(r.consumerSet(), newConsumerName)
+			pipe.Set(r.lastPosition(newConsumerName), lastPositionID, time.Duration(0))
+			pipe.Set(r.heart(newConsumerName), 1, time.Duration(oldCursor.HeartTimeout))
+			pipe.Set(r.epoch(newConsumerName), newEpoch, time.Duration(0))
+
+			if len(attempts) > 0 {
+				values := make(map[string]interface{}, len(attempts))
+
+				for field, value := range attempts {
+					values[field] = value
+				}
+
+				pipe.Del(r.attempts(oldCursor.Name))
+				pipe.HMSet(r.attempts(newConsumerName), values)
+			}
+
 			return nil
 
 		})
 
 		return err
 
-	}, lastPosition(oldCursor.Name))
+	}, r.lastPosition(oldCursor.Name), r.heart(oldCursor.Name), r.epoch(oldCursor.Name))
 }
 
-func lastPosition(ID string) string {
-	return lastPositionKey + ID
+//ReleaseCursor removes the heart of the consumer so its cursor can be stolen right away.
+func (r RedisRepository) ReleaseCursor(ctx context.Context, name string) error {
+	err := r.client(ctx).Del(r.heart(name)).Err()
+
+	if err != nil {
+		return wrapError("ReleaseCursor", err)
+	}
+
+	return nil
 }
 
-func heart(ID string) string {
-	return heartKey + ID
+//blockFor returns how long a blocking read may wait without outliving the context.
+//The client does not observe contexts so the deadline has to be enforced by hand.
+func blockFor(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	deadline, ok := ctx.Deadline()
+
+	if !ok {
+		return readBlock, nil
+	}
+
+	block := time.Until(deadline)
+
+	//Blocking for 0 means waiting forever, so a spent deadline must not get that far.
+	if block < time.Millisecond {
+		return 0, context.DeadlineExceeded
+	}
+
+	if block > readBlock {
+		return readBlock, nil
+	}
+
+	return block, nil
 }
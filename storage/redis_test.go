@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"testing"
@@ -38,11 +39,26 @@ func TestAddEntry(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		err = storage.AddEntry(entry)
+		ID, err := storage.AddEntry(context.Background(), entry)
 
 		assert.Nil(t, err, "Error is not nil")
-		assert.Equal(t, mockClient.XAddArgs.Stream, streamName, "Stream name not correct")
+		assert.Equal(t, "result", ID, "ID not correct")
+		assert.Equal(t, mockClient.XAddArgs.Stream, DefaultStream, "Stream name not correct")
 		assert.Equal(t, mockClient.XAddArgs.Values, values, "Values not correct")
+		assert.Equal(t, int64(0), mockClient.XAddArgs.MaxLenApprox, "Stream trimmed")
+	})
+
+	t.Run("Add entry with maximum length", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAddReturnStringCmd: redis.NewStringResult("result", nil),
+		}
+
+		storage := RedisRepository{Client: mockClient, MaxLen: 1000}
+
+		_, err := storage.AddEntry(context.Background(), domain.Entry{})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, int64(1000), mockClient.XAddArgs.MaxLenApprox, "Maximum length not correct")
 	})
 
 	t.Run("Xadd error", func(t *testing.T) {
@@ -63,19 +79,117 @@ func TestAddEntry(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		err = storage.AddEntry(entry)
+		ID, err := storage.AddEntry(context.Background(), entry)
 
 		assert.NotNil(t, err, "Error is not nil")
-		assert.Equal(t, mockClient.XAddArgs.Stream, streamName, "Stream name not correct")
+		assert.Empty(t, ID, "ID is not empty")
+		assert.Equal(t, mockClient.XAddArgs.Stream, DefaultStream, "Stream name not correct")
 		assert.Equal(t, mockClient.XAddArgs.Values, values, "Values not correct")
 	})
 }
 
+func TestGetEntry(t *testing.T) {
+	t.Run("Entry does not exist", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		_, err := storage.GetEntry(context.Background(), "1-0")
+
+		assert.Equal(t, domain.ErrNotFound, err, "Error is not domain.ErrNotFound")
+		assert.Equal(t, DefaultStream, mockClient.XRangeNStream, "Stream name not correct")
+		assert.Equal(t, "1-0", mockClient.XRangeNStart, "Range start not correct")
+		assert.Equal(t, "1-0", mockClient.XRangeNStop, "Range stop not correct")
+	})
+}
+
+func TestParseEntry(t *testing.T) {
+	t.Run("Valid entry", func(t *testing.T) {
+		entry, err := parseEntry(redis.XMessage{
+			ID:     "1-0",
+			Values: map[string]interface{}{entryField: `{"object_id":42,"action":"create"}`},
+		})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, domain.Entry{ID: "1-0", ObjectID: 42, Action: "create"}, entry, "Entry not correct")
+	})
+
+	t.Run("Missing entry field", func(t *testing.T) {
+		_, err := parseEntry(redis.XMessage{ID: "1-0", Values: map[string]interface{}{}})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Malformed entry", func(t *testing.T) {
+		_, err := parseEntry(redis.XMessage{
+			ID:     "1-0",
+			Values: map[string]interface{}{entryField: "{"},
+		})
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestHandleFaultyEntry(t *testing.T) {
+	t.Run("Moved in one script", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			EvalReturnCmd: redis.NewCmdResult(int64(1), nil),
+		}
+		r := RedisRepository{Client: mockClient}
+
+		r.handleFaultyEntry(context.Background(), DefaultStream, "1-0", map[string]interface{}{entryField: "{"}, "malformed entry")
+
+		require.Len(t, mockClient.EvalArgs, 3, "Args not correct")
+		assert.Equal(t, moveFaultyEntryScript, mockClient.EvalScript, "Script not correct")
+		assert.Equal(t, []string{DefaultStream, DefaultDeadLetterStream}, mockClient.EvalKeys, "Keys not correct")
+		assert.Equal(t, "1-0", mockClient.EvalArgs[0], "ID not correct")
+		assert.Equal(t, deadEntryField, mockClient.EvalArgs[1], "Field not correct")
+
+		var record domain.DeadEntry
+
+		require.Nil(t, json.Unmarshal([]byte(mockClient.EvalArgs[2].(string)), &record), "Record is not JSON")
+		assert.Equal(t, "1-0", record.OriginalID, "Original ID not correct")
+		assert.Equal(t, DefaultStream, record.Stream, "Stream not correct")
+		assert.Equal(t, "malformed entry", record.Reason, "Reason not correct")
+		assert.Equal(t, map[string]string{entryField: "{"}, record.Values, "Values not correct")
+	})
+}
+
+func TestAddEntries(t *testing.T) {
+	t.Run("No entries", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{}
+
+		storage := getTestStorage(mockClient)
+
+		results, err := storage.AddEntries(context.Background(), nil)
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, results, "Results are not empty")
+	})
+
+	t.Run("Pipeline error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			PipelineReturnPipeliner: redis.NewClient(&redis.Options{Addr: "localhost:0"}).Pipeline(),
+		}
+
+		storage := getTestStorage(mockClient)
+
+		results, err := storage.AddEntries(context.Background(), []domain.Entry{domain.Entry{ObjectID: 42}, domain.Entry{Meta: json.RawMessage("{")}})
+
+		assert.NotNil(t, err, "Error is nil")
+		require.Len(t, results, 2, "Results not correct")
+		assert.NotNil(t, results[0].Err, "Failed entry has no error")
+		assert.Contains(t, results[1].Err.Error(), "json", "Entry which could not be encoded has no error")
+	})
+}
+
 func TestGetCursors(t *testing.T) {
 	t.Run("Get cursors", func(t *testing.T) {
 		results := []string{
-			"", "name", "123",
-			"1", "otherName", "42",
+			"", "name", "123", "",
+			"1", "otherName", `{"eventStream":"42","events:3":"43"}`, "7",
 		}
 		mockClient := &mock.TestRedisClient{
 			SortReturnStringSliceCmd: redis.NewStringSliceResult(results, nil),
@@ -83,24 +197,26 @@ func TestGetCursors(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		cursors, err := storage.GetCursors()
+		cursors, err := storage.GetCursors(context.Background())
 
-		assert.Equal(t, len(cursors), len(results)/3, "Cursor count doesn't match the results")
+		assert.Equal(t, len(cursors), len(results)/cursorFields, "Cursor count doesn't match the results")
 		assert.Nil(t, err, "Error is not nil")
 
 		firstCursor := cursors[0]
-		firstResult := results[0:3]
+		firstResult := results[0:cursorFields]
 
 		assert.Equal(t, firstCursor.Name, firstResult[1], "First cursor Name not correct")
-		assert.Equal(t, firstCursor.LastID, firstResult[2], "First cursor LastID not correct")
+		assert.Equal(t, map[string]string{DefaultStream: "123"}, firstCursor.Positions, "First cursor Positions not correct")
 		assert.Equal(t, firstCursor.HasHeart, firstResult[0] != "", "First cursor HasHeart not correct")
+		assert.Equal(t, int64(0), firstCursor.Epoch, "First cursor Epoch not correct")
 
 		secondCursor := cursors[1]
-		secondResult := results[3:]
+		secondResult := results[cursorFields:]
 
 		assert.Equal(t, secondCursor.Name, secondResult[1], "Second cursor Name not correct")
-		assert.Equal(t, secondCursor.LastID, secondResult[2], "Second cursor LastID not correct")
+		assert.Equal(t, map[string]string{DefaultStream: "42", "events:3": "43"}, secondCursor.Positions, "Second cursor Positions not correct")
 		assert.Equal(t, secondCursor.HasHeart, secondResult[0] != "", "Second cursor HasHeart not correct")
+		assert.Equal(t, int64(7), secondCursor.Epoch, "Second cursor Epoch not correct")
 
 	})
 
@@ -111,7 +227,7 @@ func TestGetCursors(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		cursors, err := storage.GetCursors()
+		cursors, err := storage.GetCursors(context.Background())
 
 		assert.Nil(t, err, "Error is not nil")
 		assert.Empty(t, cursors, "Cursors are not empty")
@@ -124,15 +240,15 @@ func TestGetCursors(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		cursors, err := storage.GetCursors()
+		cursors, err := storage.GetCursors(context.Background())
 
 		assert.NotNil(t, err, "Error is nil")
 		assert.Empty(t, cursors, "Cursors are not empty")
 	})
 
-	t.Run("Sort returns results that make no sense (less than 3 strings)", func(t *testing.T) {
+	t.Run("Sort returns results that make no sense (less than 4 strings)", func(t *testing.T) {
 		results := []string{
-			"", "name",
+			"", "name", "123",
 		}
 		mockClient := &mock.TestRedisClient{
 			SortReturnStringSliceCmd: redis.NewStringSliceResult(results, nil),
@@ -140,9 +256,51 @@ func TestGetCursors(t *testing.T) {
 
 		storage := getTestStorage(mockClient)
 
-		cursors, err := storage.GetCursors()
+		cursors, err := storage.GetCursors(context.Background())
 
 		assert.Nil(t, err, "Error is not nil")
 		assert.Empty(t, cursors, "Cursors are not empty")
 	})
 }
+
+func TestBlockFor(t *testing.T) {
+	t.Run("No deadline", func(t *testing.T) {
+		block, err := blockFor(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, readBlock, block, "Block should default to readBlock")
+	})
+
+	t.Run("Deadline before readBlock", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), readBlock/2)
+		defer cancel()
+
+		block, err := blockFor(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, block <= readBlock/2, "Block should not outlive the deadline")
+		assert.True(t, block > 0, "Block must not be 0 since that blocks forever")
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := blockFor(ctx)
+
+		assert.Equal(t, context.Canceled, err, "Error should be context.Canceled")
+	})
+
+	t.Run("Cancelled context does not read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mockClient := &mock.TestRedisClient{}
+		storage := getTestStorage(mockClient)
+
+		_, _, err := storage.GetEntries(ctx, nil)
+
+		assert.Error(t, err, "Error should be returned")
+		assert.Nil(t, mockClient.XReadArgs, "XRead should not be called")
+	})
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	//trimBatch is the number of entries removed in one round trip when trimming
+	trimBatch int64 = 100
+)
+
+//TrimEntries removes the entries with IDs up to and including maxID from the stream.
+//Entries are removed in batches so a long trim does not block Redis.
+//Returns the number of removed entries, also when the trim is interrupted by an error.
+func (r RedisRepository) TrimEntries(ctx context.Context, stream, maxID string) (int64, error) {
+	var trimmed int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return trimmed, wrapError("TrimEntries", err)
+		}
+
+		messages, err := r.client(ctx).XRangeN(r.streamKey(stream), rangeStart, maxID, trimBatch).Result()
+
+		if err != nil && err != redis.Nil {
+			return trimmed, wrapError("TrimEntries", err)
+		}
+
+		if len(messages) == 0 {
+			return trimmed, nil
+		}
+
+		//XDel is not in the official version of the library so the command is built by hand.
+		args := make([]interface{}, 0, len(messages)+2)
+		args = append(args, "xdel", r.streamKey(stream))
+
+		for _, m := range messages {
+			args = append(args, m.ID)
+		}
+
+		deleted, err := r.client(ctx).Do(args...).Int64()
+
+		if err != nil {
+			return trimmed, wrapError("TrimEntries", err)
+		}
+
+		trimmed += deleted
+
+		if int64(len(messages)) < trimBatch {
+			return trimmed, nil
+		}
+	}
+}
+
+//HoldEntries keeps the entries of the stream from the ID onwards from being trimmed.
+//The hold is named so its owner can move it forward, a later call replaces the ID.
+func (r RedisRepository) HoldEntries(ctx context.Context, stream, name, ID string) error {
+	err := r.client(ctx).HSet(r.holds(stream), name, ID).Err()
+
+	if err != nil {
+		return wrapError("HoldEntries", err)
+	}
+
+	return nil
+}
+
+//GetHolds returns the IDs of the retention holds on the stream by hold name.
+func (r RedisRepository) GetHolds(ctx context.Context, stream string) (map[string]string, error) {
+	holds, err := r.client(ctx).HGetAll(r.holds(stream)).Result()
+
+	if err != nil {
+		return nil, wrapError("GetHolds", err)
+	}
+
+	return holds, nil
+}
+
+func (r RedisRepository) maxLen() int64 {
+	if r.MaxLen < 0 {
+		return 0
+	}
+
+	return r.MaxLen
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimEntries(t *testing.T) {
+	t.Run("Nothing to trim", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		storage := RedisRepository{Client: mockClient, Config: Config{Namespace: "ns"}}
+
+		trimmed, err := storage.TrimEntries(context.Background(), "orders", "5-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, int64(0), trimmed, "Trimmed count not correct")
+		assert.Equal(t, "ns:orders", mockClient.XRangeNStream, "Stream name not correct")
+		assert.Equal(t, rangeStart, mockClient.XRangeNStart, "Range start not correct")
+		assert.Equal(t, "5-0", mockClient.XRangeNStop, "Range stop not correct")
+		assert.Equal(t, trimBatch, mockClient.XRangeNCount, "Batch size not correct")
+		assert.Nil(t, mockClient.DoArgs, "XDEL was called")
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mockClient := &mock.TestRedisClient{}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.TrimEntries(ctx, DefaultStream, "5-0")
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, mockClient.XRangeNStream, "Stream was read")
+	})
+}
+
+func TestHolds(t *testing.T) {
+	t.Run("Hold entries", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			HSetReturnBoolCmd: redis.NewBoolResult(true, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient, Config: Config{Namespace: "ns"}}
+
+		err := storage.HoldEntries(context.Background(), "orders", "archiver", "5-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "ns:holds:orders", mockClient.HSetKey, "Holds key not correct")
+		assert.Equal(t, "archiver", mockClient.HSetField, "Hold name not correct")
+		assert.Equal(t, "5-0", mockClient.HSetValue, "Hold ID not correct")
+	})
+
+	t.Run("Hold entries error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			HSetReturnBoolCmd: redis.NewBoolResult(false, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		err := storage.HoldEntries(context.Background(), "orders", "archiver", "5-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+
+	t.Run("Get holds", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			HGetAllReturnStringStringMapCmd: redis.NewStringStringMapResult(map[string]string{"archiver": "5-0"}, nil),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		holds, err := storage.GetHolds(context.Background(), "orders")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, map[string]string{"archiver": "5-0"}, holds, "Holds not correct")
+		assert.Equal(t, "holds:orders", mockClient.HGetAllKey, "Holds key not correct")
+	})
+
+	t.Run("Get holds error", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			HGetAllReturnStringStringMapCmd: redis.NewStringStringMapResult(nil, errors.New("some error")),
+		}
+
+		storage := RedisRepository{Client: mockClient}
+
+		_, err := storage.GetHolds(context.Background(), "orders")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//RouteObjectType is replaced with the object type of the entry in the route template,
+	//so "events:{object_type}" adds entries of object type 3 to the "events:3" stream.
+	RouteObjectType string = "{object_type}"
+)
+
+//route returns the name of the stream the entry is added to.
+func (r RedisRepository) route(e domain.Entry) string {
+	if r.Config.Route == "" {
+		return r.streamName()
+	}
+
+	return strings.Replace(r.Config.Route, RouteObjectType, strconv.Itoa(e.ObjectType), -1)
+}
+
+//subscriptions returns the names of the streams entries are read from.
+func (r RedisRepository) subscriptions() []string {
+	if len(r.Config.Subscriptions) == 0 {
+		return []string{r.streamName()}
+	}
+
+	return r.Config.Subscriptions
+}
+
+//queryStream returns the name and the key of the stream a query reads from. When entries are routed,
+//queries for a single object type read the stream the object type is routed to and
+//the other queries the first subscribed stream, as nothing is added to the default one.
+func (r RedisRepository) queryStream(q domain.EntryQuery) (string, string) {
+	if r.Config.Route == "" {
+		return r.streamName(), r.stream()
+	}
+
+	name := r.subscriptions()[0]
+
+	if q.ObjectType != 0 {
+		name = r.route(domain.Entry{ObjectType: q.ObjectType})
+	}
+
+	return name, r.streamKey(name)
+}
+
+//encodePositions encodes the positions of a cursor so they can be stored in a single key.
+func encodePositions(positions map[string]string) string {
+	//Maps of strings always encode.
+	content, _ := json.Marshal(positions)
+	return string(content)
+}
+
+//parsePositions decodes the positions of a cursor.
+//Values stored before routing was introduced hold the ID on the default stream.
+func (r RedisRepository) parsePositions(value string) map[string]string {
+	positions := map[string]string{}
+
+	if value == "" {
+		return positions
+	}
+
+	if !strings.HasPrefix(value, "{") {
+		positions[r.streamName()] = value
+		return positions
+	}
+
+	err := json.Unmarshal([]byte(value), &positions)
+
+	if err != nil {
+		log.Printf("Failed parsing cursor positions %s: %s\n", value, err)
+	}
+
+	return positions
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+	t.Run("No route", func(t *testing.T) {
+		r := RedisRepository{}
+
+		assert.Equal(t, DefaultStream, r.route(domain.Entry{ObjectType: 3}), "Stream not correct")
+	})
+
+	t.Run("Route by object type", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XAddReturnStringCmd: redis.NewStringResult("1-0", nil),
+		}
+
+		r := RedisRepository{
+			Client: mockClient,
+			Config: Config{Route: "events:" + RouteObjectType, Namespace: "staging"},
+		}
+
+		_, err := r.AddEntry(context.Background(), domain.Entry{ObjectType: 3})
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "staging:events:3", mockClient.XAddArgs.Stream, "Entry not routed")
+	})
+
+	t.Run("Fetch routed entry from the subscribed streams", func(t *testing.T) {
+		mockClient := &mock.TestRedisClient{
+			XRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+		}
+
+		r := RedisRepository{
+			Client: mockClient,
+			Config: Config{Route: "events:" + RouteObjectType, Subscriptions: []string{"events:2", "events:3"}},
+		}
+
+		_, err := r.GetEntry(context.Background(), "1-0")
+
+		assert.Equal(t, domain.ErrNotFound, err, "Error not correct")
+		assert.Equal(t, "events:3", mockClient.XRangeNStream, "Did not search every subscribed stream")
+		assert.Equal(t, "1-0", mockClient.XRangeNStart, "Range start not correct")
+	})
+
+	t.Run("Query routed object type", func(t *testing.T) {
+		r := RedisRepository{Config: Config{Route: "events:" + RouteObjectType}}
+
+		name, key := r.queryStream(domain.EntryQuery{ObjectType: 3})
+		assert.Equal(t, "events:3", name, "Stream name not correct")
+		assert.Equal(t, "events:3", key, "Stream key not correct")
+
+		name, _ = r.queryStream(domain.EntryQuery{})
+		assert.Equal(t, DefaultStream, name, "Query without object type does not read the default stream")
+
+		r.Config.Subscriptions = []string{"events:2", "events:3"}
+
+		name, _ = r.queryStream(domain.EntryQuery{})
+		assert.Equal(t, "events:2", name, "Query without object type does not read the first subscribed stream")
+	})
+}
+
+func TestGetEntriesSubscriptions(t *testing.T) {
+	mockClient := &mock.TestRedisClient{
+		XReadReturnXStreamSliceCmd:       redis.NewXStreamSliceCmd(),
+		XRevRangeNReturnXMessageSliceCmd: redis.NewXMessageSliceCmd(),
+	}
+
+	var repo domain.EntryRepository = &RedisRepository{
+		Client: mockClient,
+		Config: Config{Subscriptions: []string{"events:1", "events:2"}, Namespace: "staging"},
+	}
+
+	entries, positions, err := repo.GetEntries(context.Background(), map[string]string{"events:2": "5-0"})
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Empty(t, entries, "Entries are not empty")
+	assert.Equal(
+		t,
+		[]string{"staging:events:1", "staging:events:2", "0-0", "5-0"},
+		mockClient.XReadArgs.Streams,
+		"Streams not read in a single XREAD",
+	)
+	assert.Equal(t, "staging:events:1", mockClient.XRevRangeNStream, "Newest entry not read from the stream without a position")
+	assert.Equal(t, int64(1), mockClient.XRevRangeNCount, "Newest entry count not correct")
+	assert.Equal(t, map[string]string{"events:1": "0-0", "events:2": "5-0"}, positions, "Positions not correct")
+}
+
+func TestParsePositions(t *testing.T) {
+	r := RedisRepository{}
+
+	assert.Equal(t, map[string]string{}, r.parsePositions(""), "Empty positions not correct")
+	assert.Equal(t, map[string]string{DefaultStream: "1-0"}, r.parsePositions("1-0"), "Single ID not read as the default stream")
+	assert.Equal(
+		t,
+		map[string]string{"events:1": "1-0"},
+		r.parsePositions(encodePositions(map[string]string{"events:1": "1-0"})),
+		"Encoded positions not correct",
+	)
+}
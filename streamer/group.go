@@ -0,0 +1,180 @@
+package streamer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+)
+
+const (
+	//DefaultGroup is the name of the consumer group used when none is specified
+	DefaultGroup string = "grs"
+
+	newEntriesID     string = ">"
+	pendingEntriesID string = "0"
+)
+
+//GroupStreamer manages the entries stream from Redis using a consumer group.
+//Entries are load-balanced between all the streamers sharing the same group.
+type GroupStreamer struct {
+	Repo     domain.GroupRepository
+	Clock    Clock
+	Group    string
+	Consumer string
+
+	//HeartTimeout is the time after which the streamer is considered dead if its heart is not refreshed.
+	HeartTimeout time.Duration
+
+	//Metrics record the entries processed after the heart timeout when set.
+	Metrics *metrics.Registry
+
+	//mu guards the consumer name shared with the heartbeat.
+	mu        sync.Mutex
+	historyID string
+}
+
+//MarkEntryProcessed acknowledges the entry in the consumer group.
+func (g *GroupStreamer) MarkEntryProcessed(ctx context.Context, ID string) error {
+	//check if ID is over time limit and report it back
+	if isOverdue(g.Clock, ID, g.heartTimeout()) {
+		log.Printf("Consumer %s finished processing entry %s after timeout\n", g.Consumer, ID)
+		countOverdue(g.Metrics)
+	}
+
+	err := g.Repo.AckEntry(ctx, g.group(), ID)
+
+	if err != nil {
+		return fmt.Errorf("MarkEntryProcessed: %s", err.Error())
+	}
+
+	return g.Repo.StoreHeart(ctx, g.Consumer, g.heartTimeout())
+}
+
+//GetEntries fetches entries for this group consumer.
+//Entries pending for this consumer (unacknowledged or claimed from other consumers)
+//are returned before reading new ones.
+func (g *GroupStreamer) GetEntries(ctx context.Context) ([]domain.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if g.historyID == "" {
+		err := g.identify(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetEntries: %s", err.Error())
+		}
+	}
+
+	err := g.Repo.StoreHeart(ctx, g.Consumer, g.heartTimeout())
+
+	if err != nil {
+		return nil, fmt.Errorf("GetEntries: %s", err.Error())
+	}
+
+	entries, err := g.Repo.GetGroupEntries(ctx, g.group(), g.Consumer, g.historyID)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetEntries: %s", err.Error())
+	}
+
+	if len(entries) > 0 {
+		g.historyID = entries[len(entries)-1].ID
+		return entries, nil
+	}
+
+	//Pending history is exhausted, start from the beginning of it on the next pass.
+	g.historyID = pendingEntriesID
+
+	entries, err = g.Repo.GetGroupEntries(ctx, g.group(), g.Consumer, newEntriesID)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetEntries: %s", err.Error())
+	}
+
+	return entries, nil
+}
+
+//CountAttempt returns how many times the entry was delivered before the current delivery.
+//Redis counts the deliveries of pending entries, claims by other consumers included.
+func (g *GroupStreamer) CountAttempt(ctx context.Context, ID string) (int, error) {
+	pending, err := g.Repo.GetPendingEntries(ctx, g.group(), ID, 1)
+
+	if err != nil {
+		return 0, fmt.Errorf("CountAttempt: %s", err.Error())
+	}
+
+	if len(pending) == 0 || pending[0].ID != ID || pending[0].Deliveries < 1 {
+		return 0, nil
+	}
+
+	return int(pending[0].Deliveries - 1), nil
+}
+
+//Beat refreshes the heart of the consumer so its pending entries are not reclaimed while it is alive.
+//Group consumers keep their names, so unlike cursors they cannot be taken over.
+func (g *GroupStreamer) Beat(ctx context.Context) error {
+	g.mu.Lock()
+	consumer := g.Consumer
+	g.mu.Unlock()
+
+	if consumer == "" {
+		return nil
+	}
+
+	err := g.Repo.StoreHeart(ctx, consumer, g.heartTimeout())
+
+	if err != nil {
+		return fmt.Errorf("Beat: %s", err.Error())
+	}
+
+	return nil
+}
+
+//Release removes the heart of the consumer so its pending entries can be reclaimed right away.
+func (g *GroupStreamer) Release(ctx context.Context) error {
+	if g.Consumer == "" {
+		return nil
+	}
+
+	return g.Repo.RemoveHeart(ctx, g.Consumer)
+}
+
+//identify makes sure the group exists and the streamer has a consumer name.
+func (g *GroupStreamer) identify(ctx context.Context) error {
+	err := g.Repo.CreateGroup(ctx, g.group())
+
+	if err != nil {
+		return fmt.Errorf("identify: %s", err.Error())
+	}
+
+	if g.Consumer == "" {
+		g.mu.Lock()
+		g.Consumer = getUniqueName()
+		g.mu.Unlock()
+	}
+
+	g.historyID = pendingEntriesID
+	return nil
+}
+
+func (g *GroupStreamer) heartTimeout() time.Duration {
+	if g.HeartTimeout <= 0 {
+		return DefaultHeartTimeout
+	}
+
+	return g.HeartTimeout
+}
+
+func (g *GroupStreamer) group() string {
+	if g.Group == "" {
+		return DefaultGroup
+	}
+
+	return g.Group
+}
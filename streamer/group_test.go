@@ -0,0 +1,182 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupGetEntries(t *testing.T) {
+
+	t.Run("No pending entries, read new ones", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{}
+
+		streamer := &GroupStreamer{Repo: mockRepo, Group: "myGroup"}
+		entries, err := streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Empty(t, entries, "GetEntries returned entries")
+		assert.Equal(t, "myGroup", mockRepo.CreateGroupGroup, "Created wrong group")
+		assert.NotEmpty(t, mockRepo.GetGroupEntriesConsumer, "Consumer name is empty")
+		assert.Equal(t, newEntriesID, mockRepo.GetGroupEntriesLastID, "Did not read new entries")
+		assert.Equal(t, pendingEntriesID, streamer.historyID, "Pending history not reset")
+	})
+
+	t.Run("Return pending entries before new ones", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetGroupEntriesReturnEntries: []domain.Entry{
+				domain.Entry{ID: "1-0"},
+				domain.Entry{ID: "2-0"},
+			},
+		}
+
+		streamer := &GroupStreamer{Repo: mockRepo, Consumer: "me"}
+		entries, err := streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Equal(t, mockRepo.GetGroupEntriesReturnEntries, entries, "GetEntries returned wrong entries")
+		assert.Equal(t, "me", mockRepo.GetGroupEntriesConsumer, "Read as wrong consumer")
+		assert.Equal(t, pendingEntriesID, mockRepo.GetGroupEntriesLastID, "Did not read pending entries")
+		assert.Equal(t, "me", mockRepo.StoreHeartConsumer, "Did not store the heart")
+		assert.Equal(t, DefaultHeartTimeout, mockRepo.StoreHeartTimeout, "Stored heart with wrong timeout")
+
+		_, err = streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Equal(t, "2-0", mockRepo.GetGroupEntriesLastID, "Did not continue reading pending entries")
+	})
+
+	t.Run("CreateGroup returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			CreateGroupReturnError: errors.New("some error"),
+		}
+
+		streamer := &GroupStreamer{Repo: mockRepo}
+		entries, err := streamer.GetEntries(context.Background())
+
+		assert.Nil(t, entries, "GetEntries returned non-nil entries")
+		assert.NotNil(t, err, "GetEntries returned a nil err")
+	})
+
+	t.Run("GetGroupEntries returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetGroupEntriesReturnError: errors.New("some error"),
+		}
+
+		streamer := &GroupStreamer{Repo: mockRepo}
+		entries, err := streamer.GetEntries(context.Background())
+
+		assert.Nil(t, entries, "GetEntries returned non-nil entries")
+		assert.NotNil(t, err, "GetEntries returned a nil err")
+	})
+}
+
+func TestGroupMarkEntryProcessed(t *testing.T) {
+	t.Run("Ack entry", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{}
+		clock := mock.TestClock{
+			Time: time.Now(),
+		}
+
+		streamer := GroupStreamer{Repo: mockRepo, Clock: clock, Group: "myGroup"}
+		err := streamer.MarkEntryProcessed(context.Background(), "invalidID")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "myGroup", mockRepo.AckEntryGroup, "Acked in wrong group")
+		assert.Equal(t, "invalidID", mockRepo.AckEntryID, "Acked wrong ID")
+	})
+
+	t.Run("AckEntry returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			AckEntryReturnError: errors.New("some error"),
+		}
+		clock := mock.TestClock{
+			Time: time.Now(),
+		}
+
+		streamer := GroupStreamer{Repo: mockRepo, Clock: clock}
+		err := streamer.MarkEntryProcessed(context.Background(), "invalidID")
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, mockRepo.StoreHeartConsumer, "Stored heart after failed ack")
+	})
+}
+
+func TestGroupRelease(t *testing.T) {
+	mockRepo := &mock.TestGroupRepo{}
+
+	streamer := GroupStreamer{Repo: mockRepo, Consumer: "me"}
+	err := streamer.Release(context.Background())
+
+	assert.Nil(t, err, "Error is not nil")
+	assert.Equal(t, "me", mockRepo.RemoveHeartConsumer, "Removed wrong heart")
+}
+
+func TestGroupBeat(t *testing.T) {
+	t.Run("Refresh heart", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{}
+
+		streamer := &GroupStreamer{Repo: mockRepo, Consumer: "me", HeartTimeout: time.Minute}
+		err := streamer.Beat(context.Background())
+
+		assert.Nil(t, err, "Beat returned non-nil error")
+		assert.Equal(t, "me", mockRepo.StoreHeartConsumer, "Did not store the heart")
+		assert.Equal(t, time.Minute, mockRepo.StoreHeartTimeout, "Stored heart with wrong timeout")
+	})
+
+	t.Run("No consumer name yet", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{}
+
+		streamer := &GroupStreamer{Repo: mockRepo}
+		err := streamer.Beat(context.Background())
+
+		assert.Nil(t, err, "Beat returned non-nil error")
+		assert.Empty(t, mockRepo.StoreHeartConsumer, "Stored a heart without a name")
+	})
+}
+
+func TestGroupCountAttempt(t *testing.T) {
+	t.Run("Count earlier deliveries", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"1-0": []domain.PendingEntry{domain.PendingEntry{ID: "1-0", Deliveries: 3}},
+			},
+		}
+
+		streamer := &GroupStreamer{Repo: mockRepo, Group: "myGroup"}
+		previous, err := streamer.CountAttempt(context.Background(), "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 2, previous, "Earlier deliveries not correct")
+		assert.Equal(t, "myGroup", mockRepo.GetPendingEntriesGroup, "Wrong group")
+		assert.Equal(t, int64(1), mockRepo.GetPendingEntriesCount, "Wrong count")
+	})
+
+	t.Run("Entry not pending", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"1-0": []domain.PendingEntry{domain.PendingEntry{ID: "2-0", Deliveries: 3}},
+			},
+		}
+
+		streamer := &GroupStreamer{Repo: mockRepo}
+		previous, err := streamer.CountAttempt(context.Background(), "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 0, previous, "Deliveries of another entry counted")
+	})
+
+	t.Run("GetPendingEntries returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{GetPendingEntriesReturnError: errors.New("some error")}
+
+		streamer := &GroupStreamer{Repo: mockRepo}
+		_, err := streamer.CountAttempt(context.Background(), "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
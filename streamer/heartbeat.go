@@ -0,0 +1,55 @@
+package streamer
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	//DefaultHeartInterval is the time between two heartbeats when none is specified
+	DefaultHeartInterval time.Duration = 1 * time.Second
+)
+
+//Beater is implemented by streamers whose heart has to be kept alive.
+type Beater interface {
+	Beat(ctx context.Context) error
+}
+
+//Heartbeat keeps the heart of a streamer alive independently of entry processing,
+//so idle consumers and consumers busy with a slow entry are not considered dead.
+type Heartbeat struct {
+	Streamer Beater
+
+	//Interval is the time between two heartbeats, it should be well below the heart timeout.
+	Interval time.Duration
+}
+
+//Run beats on every interval until the context is done.
+//Streamers fenced out of their identity stop beating for it until they identify themselves again.
+func (h Heartbeat) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := h.Streamer.Beat(ctx)
+
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+}
+
+func (h Heartbeat) interval() time.Duration {
+	if h.Interval <= 0 {
+		return DefaultHeartInterval
+	}
+
+	return h.Interval
+}
@@ -0,0 +1,66 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBeater struct {
+	beats  int
+	errors []error
+	beat   func(beats int)
+}
+
+func (b *testBeater) Beat(ctx context.Context) error {
+	b.beats++
+
+	if b.beat != nil {
+		b.beat(b.beats)
+	}
+
+	if len(b.errors) == 0 {
+		return nil
+	}
+
+	err := b.errors[0]
+	b.errors = b.errors[1:]
+	return err
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Run("Keep beating after errors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		beater := &testBeater{
+			errors: []error{errors.New("some error"), &domain.FencedError{Name: "someName"}},
+			beat: func(beats int) {
+				if beats == 3 {
+					cancel()
+				}
+			},
+		}
+
+		heartbeat := Heartbeat{Streamer: beater, Interval: time.Millisecond}
+		heartbeat.Run(ctx)
+
+		assert.Equal(t, 3, beater.beats, "Did not keep beating after an error")
+	})
+
+	t.Run("Stop when context is done", func(t *testing.T) {
+		beater := &testBeater{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		heartbeat := Heartbeat{Streamer: beater, Interval: time.Hour}
+
+		heartbeat.Run(ctx)
+
+		assert.Equal(t, 0, beater.beats, "Beat after the context was done")
+	})
+}
@@ -0,0 +1,117 @@
+package streamer
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+)
+
+//Monitor reports the state of the streams and the cursors reading them as metrics.
+//The state is read from Redis on every scrape.
+type Monitor struct {
+	Repo domain.MonitorRepository
+
+	//Streams are the names of the streams the lag of the cursors is reported for.
+	Streams []string
+}
+
+//Register makes the registry read the state on every scrape.
+func (m Monitor) Register(r *metrics.Registry) {
+	r.OnScrape(func(ctx context.Context) {
+		m.Collect(ctx, r)
+	})
+}
+
+//Collect reads the state into the metrics of the registry.
+//Errors are logged and leave the affected metrics out.
+func (m Monitor) Collect(ctx context.Context, r *metrics.Registry) {
+	cursorCount := r.Gauge("grs_cursors", "Cursors by the state of their consumer", "state")
+	lag := r.Gauge("grs_consumer_lag_seconds", "Time between the last entry added to the stream and the last one processed by the cursor", "consumer", "stream")
+	length := r.Gauge("grs_stream_length", "Entries in the stream", "stream")
+	deadLetters := r.Gauge("grs_dead_letter_entries", "Entries in the dead letter queue")
+
+	cursorCount.Reset()
+	lag.Reset()
+	length.Reset()
+
+	cursors, err := m.Repo.GetCursors(ctx)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	if err == nil {
+		var live float64
+
+		for _, c := range cursors {
+			if c.HasHeart {
+				live++
+			}
+		}
+
+		cursorCount.Set(live, "live")
+		cursorCount.Set(float64(len(cursors))-live, "dead")
+	}
+
+	for _, stream := range m.Streams {
+		info, err := m.Repo.GetStreamInfo(ctx, stream)
+
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+
+		length.Set(float64(info.Length), stream)
+
+		for _, c := range cursors {
+			behind, ok := idDistance(c.Positions[stream], info.LastID)
+
+			if ok {
+				lag.Set(behind.Seconds(), c.Name, stream)
+			}
+		}
+	}
+
+	info, err := m.Repo.GetDeadLetterInfo(ctx)
+
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	deadLetters.Set(float64(info.Length))
+}
+
+//idDistance returns the time between adding the entries with the given IDs, 0 if the first one is not older.
+//Returns false if either of the IDs can not be parsed.
+func idDistance(from, to string) (time.Duration, bool) {
+	fromMillis, _, err := domain.ParseID(from)
+
+	if err != nil {
+		return 0, false
+	}
+
+	toMillis, _, err := domain.ParseID(to)
+
+	if err != nil {
+		return 0, false
+	}
+
+	if toMillis <= fromMillis {
+		return 0, true
+	}
+
+	return time.Duration(toMillis-fromMillis) * time.Millisecond, true
+}
+
+//countOverdue counts an entry processed after the heart timeout.
+func countOverdue(r *metrics.Registry) {
+	if r == nil {
+		return
+	}
+
+	r.Counter("grs_entries_overdue_total", "Entries processed after the heart timeout").Inc()
+}
@@ -0,0 +1,57 @@
+package streamer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitor(t *testing.T) {
+	t.Run("Report cursors, lag and stream sizes", func(t *testing.T) {
+		repo := &mock.TestMonitorRepo{
+			GetCursorsReturnCursors: []domain.StreamCursor{
+				domain.StreamCursor{Name: "slow", HasHeart: true, Positions: map[string]string{"events": "1000-0"}},
+				domain.StreamCursor{Name: "fast", HasHeart: true, Positions: map[string]string{"events": "5000-1"}},
+				domain.StreamCursor{Name: "dead"},
+			},
+			GetStreamInfoReturnInfos: map[string]domain.StreamInfo{
+				"events": domain.StreamInfo{Name: "events", Length: 12, LastID: "5000-1"},
+			},
+			GetDeadLetterInfoReturnInfo: domain.StreamInfo{Length: 3},
+		}
+		registry := metrics.NewRegistry()
+
+		Monitor{Repo: repo, Streams: []string{"events"}}.Collect(context.Background(), registry)
+
+		var b bytes.Buffer
+		registry.WriteTo(&b)
+
+		assert.Contains(t, b.String(), `grs_cursors{state="live"} 2`, "Live cursors not correct")
+		assert.Contains(t, b.String(), `grs_cursors{state="dead"} 1`, "Dead cursors not correct")
+		assert.Contains(t, b.String(), `grs_consumer_lag_seconds{consumer="slow",stream="events"} 4`, "Lag not correct")
+		assert.Contains(t, b.String(), `grs_consumer_lag_seconds{consumer="fast",stream="events"} 0`, "Lag not correct")
+		assert.Contains(t, b.String(), `grs_stream_length{stream="events"} 12`, "Stream length not correct")
+		assert.Contains(t, b.String(), "grs_dead_letter_entries 3\n", "Dead letter queue size not correct")
+	})
+
+	t.Run("Cursors error leaves cursor metrics out", func(t *testing.T) {
+		repo := &mock.TestMonitorRepo{
+			GetCursorsReturnError: errors.New("some error"),
+		}
+		registry := metrics.NewRegistry()
+
+		Monitor{Repo: repo, Streams: []string{"events"}}.Collect(context.Background(), registry)
+
+		var b bytes.Buffer
+		registry.WriteTo(&b)
+
+		assert.NotContains(t, b.String(), "grs_cursors{", "Cursors reported")
+		assert.Contains(t, b.String(), `grs_stream_length{stream="events"} 0`, "Stream length not reported")
+	})
+}
@@ -0,0 +1,173 @@
+package streamer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//DefaultMaxClaims is the number of pending entries inspected in one reclaim pass when none is specified
+	DefaultMaxClaims int64 = 100
+)
+
+//Reclaimer reassigns entries held by dead or stuck group consumers to a live consumer.
+type Reclaimer struct {
+	Repo     domain.GroupRepository
+	Group    string
+	Consumer string
+
+	//IdleThreshold is the pending age after which an entry is reclaimed even if its consumer is alive.
+	IdleThreshold time.Duration
+	//MaxClaims is the maximum number of entries reclaimed in one pass, also the size of the pages of pending entries.
+	MaxClaims int64
+	//Interval is the time between two reclaim passes.
+	Interval time.Duration
+}
+
+//Run reclaims entries on every interval until the context is done.
+func (r Reclaimer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		claimed, err := r.Reclaim(ctx)
+
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+
+		if claimed > 0 {
+			log.Printf("Consumer %s reclaimed %d entries\n", r.Consumer, claimed)
+		}
+	}
+}
+
+//Reclaim does a single pass over the pending entries of the group and claims
+//the ones whose consumer has no heart or which have been idle for too long.
+//Pending entries are read page by page until enough of them can be claimed.
+//Returns the number of claimed entries.
+func (r Reclaimer) Reclaim(ctx context.Context) (int, error) {
+	hearts := make(map[string]bool)
+	IDs := make([]string, 0, r.maxClaims())
+	var minIdle time.Duration
+
+	for start := ""; int64(len(IDs)) < r.maxClaims(); {
+		pending, err := r.Repo.GetPendingEntries(ctx, r.group(), start, r.maxClaims())
+
+		if err != nil {
+			return 0, fmt.Errorf("Reclaim: %s", err.Error())
+		}
+
+		for _, p := range pending {
+			claimable, err := r.claimable(ctx, p, hearts)
+
+			if err != nil {
+				return 0, fmt.Errorf("Reclaim: %s", err.Error())
+			}
+
+			if !claimable {
+				continue
+			}
+
+			if len(IDs) == 0 || p.Idle < minIdle {
+				minIdle = p.Idle
+			}
+
+			IDs = append(IDs, p.ID)
+
+			if int64(len(IDs)) == r.maxClaims() {
+				break
+			}
+		}
+
+		if int64(len(pending)) < r.maxClaims() {
+			break
+		}
+
+		start = domain.NextID(pending[len(pending)-1].ID)
+
+		if start == "" {
+			break
+		}
+	}
+
+	if len(IDs) == 0 {
+		return 0, nil
+	}
+
+	//Claiming with the lowest observed idle time makes sure entries
+	//claimed by someone else in the meantime are left alone.
+	entries, err := r.Repo.ClaimEntries(ctx, r.group(), r.Consumer, minIdle, IDs)
+
+	if err != nil {
+		return 0, fmt.Errorf("Reclaim: %s", err.Error())
+	}
+
+	return len(entries), nil
+}
+
+//claimable checks if the pending entry belongs to another consumer which is dead or stuck on it.
+//Hearts caches the consumers already checked.
+func (r Reclaimer) claimable(ctx context.Context, p domain.PendingEntry, hearts map[string]bool) (bool, error) {
+	if p.Consumer == r.Consumer {
+		return false, nil
+	}
+
+	alive, ok := hearts[p.Consumer]
+
+	if !ok {
+		var err error
+		alive, err = r.Repo.HasHeart(ctx, p.Consumer)
+
+		if err != nil {
+			return false, err
+		}
+
+		hearts[p.Consumer] = alive
+	}
+
+	return !alive || p.Idle >= r.idleThreshold(), nil
+}
+
+func (r Reclaimer) group() string {
+	if r.Group == "" {
+		return DefaultGroup
+	}
+
+	return r.Group
+}
+
+func (r Reclaimer) idleThreshold() time.Duration {
+	if r.IdleThreshold <= 0 {
+		return DefaultHeartTimeout
+	}
+
+	return r.IdleThreshold
+}
+
+func (r Reclaimer) maxClaims() int64 {
+	if r.MaxClaims <= 0 {
+		return DefaultMaxClaims
+	}
+
+	return r.MaxClaims
+}
+
+func (r Reclaimer) interval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultHeartTimeout
+	}
+
+	return r.Interval
+}
@@ -0,0 +1,156 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReclaim(t *testing.T) {
+
+	t.Run("Claim entries of dead and stuck consumers", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "dead", Idle: 3 * time.Second},
+					domain.PendingEntry{ID: "2-0", Consumer: "alive", Idle: time.Second},
+					domain.PendingEntry{ID: "3-0", Consumer: "alive", Idle: 2 * time.Minute},
+					domain.PendingEntry{ID: "4-0", Consumer: "me", Idle: 2 * time.Minute},
+					domain.PendingEntry{ID: "5-0", Consumer: "dead", Idle: 4 * time.Second},
+				},
+			},
+			HasHeartReturnHearts: map[string]bool{"alive": true},
+			ClaimEntriesReturnEntries: []domain.Entry{
+				domain.Entry{ID: "1-0"},
+				domain.Entry{ID: "3-0"},
+				domain.Entry{ID: "5-0"},
+			},
+		}
+
+		reclaimer := Reclaimer{
+			Repo:          mockRepo,
+			Group:         "myGroup",
+			Consumer:      "me",
+			IdleThreshold: time.Minute,
+			MaxClaims:     5,
+		}
+
+		claimed, err := reclaimer.Reclaim(context.Background())
+
+		assert.Nil(t, err, "Reclaim returned non-nil error")
+		assert.Equal(t, 3, claimed, "Reclaim returned wrong count")
+		assert.Equal(t, int64(5), mockRepo.GetPendingEntriesCount, "Fetched wrong number of pending entries")
+		assert.Equal(t, []string{"dead", "alive"}, mockRepo.HasHeartConsumers, "Checked wrong hearts")
+		assert.Equal(t, "myGroup", mockRepo.ClaimEntriesGroup, "Claimed from wrong group")
+		assert.Equal(t, "me", mockRepo.ClaimEntriesConsumer, "Claimed for wrong consumer")
+		assert.Equal(t, []string{"1-0", "3-0", "5-0"}, mockRepo.ClaimEntriesIDs, "Claimed wrong IDs")
+		assert.Equal(t, 3*time.Second, mockRepo.ClaimEntriesMinIdle, "Claimed with wrong min idle")
+	})
+
+	t.Run("Page through entries which can not be claimed", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "alive", Idle: time.Second},
+					domain.PendingEntry{ID: "2-0", Consumer: "dead", Idle: time.Second},
+				},
+				"2-1": []domain.PendingEntry{
+					domain.PendingEntry{ID: "3-0", Consumer: "alive", Idle: time.Second},
+					domain.PendingEntry{ID: "4-0", Consumer: "alive", Idle: time.Second},
+				},
+				"4-1": []domain.PendingEntry{
+					domain.PendingEntry{ID: "5-0", Consumer: "dead", Idle: time.Second},
+				},
+			},
+			HasHeartReturnHearts: map[string]bool{"alive": true},
+		}
+
+		reclaimer := Reclaimer{Repo: mockRepo, Consumer: "me", IdleThreshold: time.Minute, MaxClaims: 2}
+
+		_, err := reclaimer.Reclaim(context.Background())
+
+		assert.Nil(t, err, "Reclaim returned non-nil error")
+		assert.Equal(t, []string{"", "2-1", "4-1"}, mockRepo.GetPendingEntriesStarts, "Did not page through pending entries")
+		assert.Equal(t, []string{"2-0", "5-0"}, mockRepo.ClaimEntriesIDs, "Claimed wrong IDs")
+	})
+
+	t.Run("Stop paging once enough entries are found", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "dead", Idle: time.Second},
+					domain.PendingEntry{ID: "2-0", Consumer: "dead", Idle: time.Second},
+				},
+			},
+		}
+
+		reclaimer := Reclaimer{Repo: mockRepo, Consumer: "me", MaxClaims: 2}
+
+		_, err := reclaimer.Reclaim(context.Background())
+
+		assert.Nil(t, err, "Reclaim returned non-nil error")
+		assert.Equal(t, []string{""}, mockRepo.GetPendingEntriesStarts, "Read more pages than needed")
+		assert.Equal(t, []string{"1-0", "2-0"}, mockRepo.ClaimEntriesIDs, "Claimed wrong IDs")
+	})
+
+	t.Run("Nothing to claim", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "alive", Idle: time.Second},
+				},
+			},
+			HasHeartReturnHearts: map[string]bool{"alive": true},
+		}
+
+		reclaimer := Reclaimer{Repo: mockRepo, Consumer: "me"}
+
+		claimed, err := reclaimer.Reclaim(context.Background())
+
+		assert.Nil(t, err, "Reclaim returned non-nil error")
+		assert.Equal(t, 0, claimed, "Reclaim returned wrong count")
+		assert.Equal(t, DefaultMaxClaims, mockRepo.GetPendingEntriesCount, "Fetched wrong number of pending entries")
+		assert.Nil(t, mockRepo.ClaimEntriesIDs, "ClaimEntries was called")
+	})
+
+	t.Run("HasHeart returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "other", Idle: time.Second},
+				},
+			},
+			HasHeartReturnError: errors.New("some error"),
+		}
+
+		reclaimer := Reclaimer{Repo: mockRepo, Consumer: "me"}
+
+		claimed, err := reclaimer.Reclaim(context.Background())
+
+		assert.NotNil(t, err, "Reclaim returned a nil err")
+		assert.Equal(t, 0, claimed, "Reclaim returned wrong count")
+	})
+
+	t.Run("ClaimEntries returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestGroupRepo{
+			GetPendingEntriesReturnPages: map[string][]domain.PendingEntry{
+				"": []domain.PendingEntry{
+					domain.PendingEntry{ID: "1-0", Consumer: "dead", Idle: time.Second},
+				},
+			},
+			ClaimEntriesReturnError: errors.New("some error"),
+		}
+
+		reclaimer := Reclaimer{Repo: mockRepo, Consumer: "me"}
+
+		claimed, err := reclaimer.Reclaim(context.Background())
+
+		assert.NotNil(t, err, "Reclaim returned a nil err")
+		assert.Equal(t, 0, claimed, "Reclaim returned wrong count")
+	})
+}
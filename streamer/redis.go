@@ -1,20 +1,24 @@
 package streamer
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
 	"github.com/go-redis/redis"
 	uuid "github.com/satori/go.uuid"
 )
 
 const (
-	//ConsumerTimeout is the time alloted for processing an entry
-	ConsumerTimeout time.Duration = 5 * time.Second
+	//DefaultHeartTimeout is the time after which a silent consumer is considered dead when none is specified.
+	//It is also the time alloted for processing an entry.
+	DefaultHeartTimeout time.Duration = 5 * time.Second
 )
 
 //Clock provides the current time
@@ -32,56 +36,253 @@ func (r RealClock) Now() time.Time {
 
 //RedisStreamer manages the entries stream from Redis
 type RedisStreamer struct {
-	Repo   domain.EntryRepository
-	Clock  Clock
-	cursor domain.StreamCursor
+	Repo  domain.EntryRepository
+	Clock Clock
+
+	//HeartTimeout is the time after which the streamer is considered dead if its heart is not refreshed.
+	HeartTimeout time.Duration
+
+	//Start maps the names of the streams to the positions to replay them after, see StartFrom and StartTime.
+	//A streamer with start positions starts a new cursor at them instead of taking over a stopped one.
+	//Streams without a position are read from new entries only.
+	Start map[string]string
+
+	//Metrics record the entries processed after the heart timeout when set.
+	Metrics *metrics.Registry
+
+	//mu guards the cursor name and state shared with the heartbeat.
+	mu         sync.Mutex
+	cursor     domain.StreamCursor
+	registered bool
+	fenced     bool
+	started    bool
+
+	//processed holds the last processed ID per stream while the cursor positions hold the last read ones.
+	processed map[string]string
+	//streams maps the IDs of the entries read but not yet processed to their streams.
+	streams map[string]string
 }
 
 //MarkEntryProcessed stores info about the streamer and last ID processed.
-func (r RedisStreamer) MarkEntryProcessed(ID string) error {
+//Returns a domain.FencedError if another consumer has taken over the cursor.
+func (r *RedisStreamer) MarkEntryProcessed(ctx context.Context, ID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fenced {
+		return &domain.FencedError{Name: r.cursor.Name, Epoch: r.cursor.Epoch}
+	}
+
 	//check if ID is over time limit and report it back
 	if r.isAckOverdue(ID) {
 		log.Printf("Consumer %s finished processing entry %s after timeout\n", r.cursor.Name, ID)
+		countOverdue(r.Metrics)
+	}
+
+	stream, ok := r.streams[ID]
+
+	if !ok {
+		return fmt.Errorf("MarkEntryProcessed: entry %s was not read by this streamer", ID)
+	}
+
+	positions := make(map[string]string, len(r.processed)+1)
+
+	for s, processedID := range r.processed {
+		positions[s] = processedID
 	}
 
-	return r.Repo.StoreCursor(domain.StreamCursor{
+	positions[stream] = ID
+
+	err := r.Repo.StoreCursor(ctx, domain.StreamCursor{
 		Name:         r.cursor.Name,
-		LastID:       ID,
-		HeartTimeout: int64(ConsumerTimeout),
+		Positions:    positions,
+		HeartTimeout: int64(r.heartTimeout()),
+		Epoch:        r.cursor.Epoch,
 	})
+
+	if domain.IsFenced(err) {
+		log.Printf("Consumer %s was fenced out of its cursor by another consumer\n", r.cursor.Name)
+		r.fenced = true
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	delete(r.streams, ID)
+	r.processed = positions
+	r.registered = true
+	return nil
 }
 
 //GetEntries fetches events from Redis Stream.
-func (r *RedisStreamer) GetEntries() ([]domain.Entry, error) {
+//A streamer which was fenced out of its cursor identifies itself again before reading.
+func (r *RedisStreamer) GetEntries(ctx context.Context) ([]domain.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.isFenced() {
+		r.reset()
+	}
+
 	if r.cursor.Name == "" {
-		err := r.identify()
+		err := r.identify(ctx)
 
 		if err != nil {
 			return nil, fmt.Errorf("GetEntries: %s", err.Error())
 		}
 	}
 
-	entries, lastID, err := r.Repo.GetEntries(r.cursor.LastID)
+	entries, positions, err := r.Repo.GetEntries(ctx, r.cursor.Positions)
 
 	if err != nil {
 		return nil, fmt.Errorf("GetEntries: %s", err.Error())
 	}
 
-	if lastID != "" {
-		r.cursor.LastID = lastID
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cursor.Positions = positions
+	r.streams = make(map[string]string, len(entries))
+
+	for _, e := range entries {
+		r.streams[e.ID] = e.Stream
 	}
 
+	r.startStreams(entries, positions)
+
 	return entries, nil
 }
 
+//startStreams records the position a stream was first read from as processed, so the cursor is stored
+//with a position on every stream and a consumer taking it over does not skip entries of a quiet stream.
+func (r *RedisStreamer) startStreams(entries []domain.Entry, positions map[string]string) {
+	starts := make(map[string]string, len(positions))
+
+	for stream, ID := range positions {
+		if _, ok := r.processed[stream]; !ok {
+			starts[stream] = ID
+		}
+	}
+
+	if len(starts) == 0 {
+		return
+	}
+
+	//Entries read from a stream start after the position, the first one is not processed yet.
+	for i := len(entries) - 1; i >= 0; i-- {
+		if _, ok := starts[entries[i].Stream]; ok {
+			starts[entries[i].Stream] = domain.PreviousID(entries[i].ID)
+		}
+	}
+
+	processed := make(map[string]string, len(r.processed)+len(starts))
+
+	for stream, ID := range r.processed {
+		processed[stream] = ID
+	}
+
+	for stream, ID := range starts {
+		if ID != "" {
+			processed[stream] = ID
+		}
+	}
+
+	r.processed = processed
+}
+
+//CountAttempt records the start of processing the entry with the cursor.
+//Returns how many times processing it was started before, also by a consumer the cursor was taken over from.
+func (r *RedisStreamer) CountAttempt(ctx context.Context, ID string) (int, error) {
+	r.mu.Lock()
+	name, stream := r.cursor.Name, r.streams[ID]
+	r.mu.Unlock()
+
+	previous, err := r.Repo.CountAttempt(ctx, name, stream, ID)
+
+	if err != nil {
+		return 0, fmt.Errorf("CountAttempt: %s", err.Error())
+	}
+
+	return previous, nil
+}
+
+//Beat refreshes the heart of the cursor so it is not taken over while the streamer is alive.
+//Returns a domain.FencedError if another consumer has taken over the cursor.
+func (r *RedisStreamer) Beat(ctx context.Context) error {
+	r.mu.Lock()
+	cursor, registered, fenced := r.cursor, r.registered, r.fenced
+	r.mu.Unlock()
+
+	//Other consumers only see the cursor once it is stored.
+	if !registered || fenced {
+		return nil
+	}
+
+	err := r.Repo.RefreshCursor(ctx, domain.StreamCursor{
+		Name:         cursor.Name,
+		HeartTimeout: int64(r.heartTimeout()),
+		Epoch:        cursor.Epoch,
+	})
+
+	if domain.IsFenced(err) {
+		log.Printf("Consumer %s was fenced out of its cursor by another consumer\n", cursor.Name)
+
+		r.mu.Lock()
+		//The streamer could have identified itself again in the meantime.
+		if r.cursor.Name == cursor.Name {
+			r.fenced = true
+		}
+		r.mu.Unlock()
+
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("Beat: %s", err.Error())
+	}
+
+	return nil
+}
+
+//Release gives up the cursor so another consumer can continue from the last processed entry right away.
+func (r *RedisStreamer) Release(ctx context.Context) error {
+	if r.cursor.Name == "" {
+		return nil
+	}
+
+	//The cursor belongs to another consumer now.
+	if r.isFenced() {
+		return nil
+	}
+
+	return r.Repo.ReleaseCursor(ctx, r.cursor.Name)
+}
+
 //identify trys to assume the name and position of the consumer which has stopped.
-func (r *RedisStreamer) identify() error {
-	cursors, err := r.Repo.GetCursors()
+//The first cursor of a streamer with start positions starts at them instead.
+func (r *RedisStreamer) identify(ctx context.Context) error {
+	if len(r.Start) > 0 && !r.started {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.cursor.Name, r.cursor.Positions, r.cursor.Epoch = getUniqueName(), copyPositions(r.Start), 0
+		r.processed = map[string]string{}
+		r.started = true
+		return nil
+	}
+
+	cursors, err := r.Repo.GetCursors(ctx)
 
 	if err != nil {
 		return fmt.Errorf("identify: %s", err.Error())
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for _, cursor := range cursors {
 
 		//Skip consumer which is still alive.
@@ -90,8 +291,8 @@ func (r *RedisStreamer) identify() error {
 		}
 
 		r.cursor.Name = getUniqueName()
-		cursor.HeartTimeout = int64(ConsumerTimeout)
-		err := r.Repo.StealCursor(cursor, r.cursor.Name)
+		cursor.HeartTimeout = int64(r.heartTimeout())
+		err := r.Repo.StealCursor(ctx, cursor, r.cursor.Name)
 
 		//Consumer is still alive, skip him.
 		if err == redis.TxFailedErr {
@@ -102,15 +303,52 @@ func (r *RedisStreamer) identify() error {
 			return fmt.Errorf("identify: %s", err.Error())
 		}
 
-		r.cursor.LastID = cursor.LastID
+		r.cursor.Positions = cursor.Positions
+		r.cursor.Epoch = cursor.Epoch + 1
+		r.processed = cursor.Positions
+		r.registered = true
 		return nil
 	}
 
-	r.cursor.Name, r.cursor.LastID = getUniqueName(), "$"
+	//Without positions the streamer reads new entries only.
+	r.cursor.Name, r.cursor.Positions, r.cursor.Epoch = getUniqueName(), nil, 0
+	r.processed = map[string]string{}
 	return nil
 }
 
-func (r RedisStreamer) isAckOverdue(ID string) bool {
+//reset forgets the cursor the streamer was fenced out of so it identifies itself again.
+func (r *RedisStreamer) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log.Printf("Consumer %s is identifying itself again\n", r.cursor.Name)
+
+	r.cursor = domain.StreamCursor{}
+	r.registered, r.fenced = false, false
+	r.processed, r.streams = nil, nil
+}
+
+func (r *RedisStreamer) isFenced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fenced
+}
+
+func (r *RedisStreamer) isAckOverdue(ID string) bool {
+	return isOverdue(r.Clock, ID, r.heartTimeout())
+}
+
+func (r *RedisStreamer) heartTimeout() time.Duration {
+	if r.HeartTimeout <= 0 {
+		return DefaultHeartTimeout
+	}
+
+	return r.HeartTimeout
+}
+
+//isOverdue checks if more than timeout passed since the entry with the given ID was added.
+func isOverdue(clock Clock, ID string, timeout time.Duration) bool {
 	parts := strings.Split(ID, "-")
 
 	millis, err := strconv.Atoi(parts[0])
@@ -125,7 +363,17 @@ func (r RedisStreamer) isAckOverdue(ID string) bool {
 		int64(millis)*int64(time.Millisecond),
 	)
 
-	return r.Clock.Now().Sub(IDTime) > ConsumerTimeout
+	return clock.Now().Sub(IDTime) > timeout
+}
+
+func copyPositions(positions map[string]string) map[string]string {
+	c := make(map[string]string, len(positions))
+
+	for stream, ID := range positions {
+		c[stream] = ID
+	}
+
+	return c
 }
 
 func getUniqueName() string {
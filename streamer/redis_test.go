@@ -1,6 +1,8 @@
 package streamer
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
@@ -12,8 +14,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func getTestStreamer(mockRepo domain.EntryRepository, clock Clock) RedisStreamer {
-	return RedisStreamer{
+func getTestStreamer(mockRepo domain.EntryRepository, clock Clock) *RedisStreamer {
+	return &RedisStreamer{
 		Repo:  mockRepo,
 		Clock: clock,
 	}
@@ -29,22 +31,22 @@ func TestGetEntries(t *testing.T) {
 					ObjectID:   123,
 					ObjectType: 3,
 					Action:     "create",
-					Meta:       "JSON",
+					Meta:       json.RawMessage(`{"name":"my object"}`),
 				},
 			},
-			GetEntriesReturnLastID: "myLastID",
-			GetEntriesReturnError:  nil,
+			GetEntriesReturnPositions: map[string]string{"eventStream": "myLastID"},
+			GetEntriesReturnError:     nil,
 
 			GetCursorsReturnCursors: []domain.StreamCursor{
 				domain.StreamCursor{
-					Name:     "myCursor",
-					LastID:   "myID",
-					HasHeart: true,
+					Name:      "myCursor",
+					Positions: map[string]string{"eventStream": "myID"},
+					HasHeart:  true,
 				},
 				domain.StreamCursor{
-					Name:     "foo",
-					LastID:   "bar",
-					HasHeart: false,
+					Name:      "foo",
+					Positions: map[string]string{"eventStream": "bar"},
+					HasHeart:  false,
 				},
 			},
 			GetCursorsReturnError: nil,
@@ -55,14 +57,14 @@ func TestGetEntries(t *testing.T) {
 		cursorForStealCursor := mockRepo.GetCursorsReturnCursors[1]
 
 		streamer := getTestStreamer(mockRepo, nil)
-		entries, err := streamer.GetEntries()
+		entries, err := streamer.GetEntries(context.Background())
 
 		assert.NotEmpty(t, streamer.cursor.Name, "Cursor name is empty")
 		assert.Equal(
 			t,
-			streamer.cursor.LastID,
-			mockRepo.GetEntriesReturnLastID,
-			"GetEntries returned wrong LastID",
+			streamer.cursor.Positions,
+			mockRepo.GetEntriesReturnPositions,
+			"GetEntries returned wrong Positions",
 		)
 
 		assert.Equal(
@@ -73,13 +75,13 @@ func TestGetEntries(t *testing.T) {
 		)
 		assert.Equal(
 			t,
-			cursorForStealCursor.LastID,
-			mockRepo.StealCursorOldCursor.LastID,
-			"StealCursor old cursor LastID wrong",
+			cursorForStealCursor.Positions,
+			mockRepo.StealCursorOldCursor.Positions,
+			"StealCursor old cursor Positions wrong",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StealCursorOldCursor.HeartTimeout,
 			"StealCursor old cursor HeartTimeout wrong",
 		)
@@ -101,7 +103,7 @@ func TestGetEntries(t *testing.T) {
 
 		streamer := getTestStreamer(mockRepo, nil)
 
-		entries, err := streamer.GetEntries()
+		entries, err := streamer.GetEntries(context.Background())
 
 		assert.Nil(t, entries, "GetEntries returned non-nil entries")
 		assert.NotNil(t, err, "GetEntries returned a nil err")
@@ -117,7 +119,7 @@ func TestGetEntries(t *testing.T) {
 
 		streamer := getTestStreamer(mockRepo, nil)
 
-		entries, err := streamer.GetEntries()
+		entries, err := streamer.GetEntries(context.Background())
 
 		assert.Nil(t, entries, "GetEntries returned non-nil entries")
 		assert.NotNil(t, err, "GetEntries returned a nil err")
@@ -128,9 +130,9 @@ func TestGetEntries(t *testing.T) {
 		mockRepo := &mock.TestRepo{
 			GetCursorsReturnCursors: []domain.StreamCursor{
 				domain.StreamCursor{
-					Name:     "foo",
-					LastID:   "bar",
-					HasHeart: false,
+					Name:      "foo",
+					Positions: map[string]string{"eventStream": "bar"},
+					HasHeart:  false,
 				},
 			},
 			GetCursorsReturnError:  nil,
@@ -140,7 +142,7 @@ func TestGetEntries(t *testing.T) {
 
 		streamer := getTestStreamer(mockRepo, nil)
 
-		entries, err := streamer.GetEntries()
+		entries, err := streamer.GetEntries(context.Background())
 
 		assert.Equal(
 			t,
@@ -150,13 +152,13 @@ func TestGetEntries(t *testing.T) {
 		)
 		assert.Equal(
 			t,
-			cursorForStealCursor.LastID,
-			mockRepo.StealCursorOldCursor.LastID,
-			"StealCursor old cursor LastID wrong",
+			cursorForStealCursor.Positions,
+			mockRepo.StealCursorOldCursor.Positions,
+			"StealCursor old cursor Positions wrong",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StealCursorOldCursor.HeartTimeout,
 			"StealCursor old cursor HeartTimeout wrong",
 		)
@@ -169,9 +171,9 @@ func TestGetEntries(t *testing.T) {
 		mockRepo := &mock.TestRepo{
 			GetCursorsReturnCursors: []domain.StreamCursor{
 				domain.StreamCursor{
-					Name:     "foo",
-					LastID:   "bar",
-					HasHeart: false,
+					Name:      "foo",
+					Positions: map[string]string{"eventStream": "bar"},
+					HasHeart:  false,
 				},
 			},
 			GetCursorsReturnError:  nil,
@@ -181,7 +183,7 @@ func TestGetEntries(t *testing.T) {
 
 		streamer := getTestStreamer(mockRepo, nil)
 
-		entries, err := streamer.GetEntries()
+		entries, err := streamer.GetEntries(context.Background())
 
 		assert.Equal(
 			t,
@@ -191,13 +193,13 @@ func TestGetEntries(t *testing.T) {
 		)
 		assert.Equal(
 			t,
-			cursorForStealCursor.LastID,
-			mockRepo.StealCursorOldCursor.LastID,
-			"StealCursor old cursor LastID wrong",
+			cursorForStealCursor.Positions,
+			mockRepo.StealCursorOldCursor.Positions,
+			"StealCursor old cursor Positions wrong",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StealCursorOldCursor.HeartTimeout,
 			"StealCursor old cursor HeartTimeout wrong",
 		)
@@ -206,7 +208,7 @@ func TestGetEntries(t *testing.T) {
 		assert.Nil(t, err, "GetEntries returned a nil err")
 
 		assert.NotEmpty(t, streamer.cursor.Name, "Cursor name is empty")
-		assert.Equal(t, "$", streamer.cursor.LastID, "Cursor LastID is not $")
+		assert.Nil(t, streamer.cursor.Positions, "New cursor has positions")
 	})
 }
 
@@ -224,8 +226,9 @@ func TestMarkEntryProcessed(t *testing.T) {
 
 		streamer := getTestStreamer(mockRepo, clock)
 		streamer.cursor.Name = streamerName
+		streamer.streams = map[string]string{ID: "eventStream"}
 
-		err := streamer.MarkEntryProcessed(ID)
+		err := streamer.MarkEntryProcessed(context.Background(), ID)
 
 		assert.Equal(
 			t,
@@ -236,12 +239,12 @@ func TestMarkEntryProcessed(t *testing.T) {
 		assert.Equal(
 			t,
 			ID,
-			mockRepo.StoreCursorCursor.LastID,
+			mockRepo.StoreCursorCursor.Positions["eventStream"],
 			"Did not store the correct ID",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StoreCursorCursor.HeartTimeout,
 			"Did not store the correct HeartTimeout",
 		)
@@ -255,15 +258,16 @@ func TestMarkEntryProcessed(t *testing.T) {
 			StoreCursorReturnError: nil,
 		}
 		clock := mock.TestClock{
-			Time: time.Now().Add(2 * ConsumerTimeout),
+			Time: time.Now().Add(2 * DefaultHeartTimeout),
 		}
 		ID := fmt.Sprintf("%d-0", time.Now().Unix()*1000)
 		streamerName := "someName"
 
 		streamer := getTestStreamer(mockRepo, clock)
 		streamer.cursor.Name = streamerName
+		streamer.streams = map[string]string{ID: "eventStream"}
 
-		err := streamer.MarkEntryProcessed(ID)
+		err := streamer.MarkEntryProcessed(context.Background(), ID)
 
 		assert.Equal(
 			t,
@@ -274,12 +278,12 @@ func TestMarkEntryProcessed(t *testing.T) {
 		assert.Equal(
 			t,
 			ID,
-			mockRepo.StoreCursorCursor.LastID,
+			mockRepo.StoreCursorCursor.Positions["eventStream"],
 			"Did not store the correct ID",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StoreCursorCursor.HeartTimeout,
 			"Did not store the correct HeartTimeout",
 		)
@@ -293,15 +297,16 @@ func TestMarkEntryProcessed(t *testing.T) {
 			StoreCursorReturnError: nil,
 		}
 		clock := mock.TestClock{
-			Time: time.Now().Add(ConsumerTimeout * -2),
+			Time: time.Now().Add(DefaultHeartTimeout * -2),
 		}
 		ID := "invalidID"
 		streamerName := "someName"
 
 		streamer := getTestStreamer(mockRepo, clock)
 		streamer.cursor.Name = streamerName
+		streamer.streams = map[string]string{ID: "eventStream"}
 
-		err := streamer.MarkEntryProcessed(ID)
+		err := streamer.MarkEntryProcessed(context.Background(), ID)
 
 		assert.Equal(
 			t,
@@ -312,12 +317,12 @@ func TestMarkEntryProcessed(t *testing.T) {
 		assert.Equal(
 			t,
 			ID,
-			mockRepo.StoreCursorCursor.LastID,
+			mockRepo.StoreCursorCursor.Positions["eventStream"],
 			"Did not store the correct ID",
 		)
 		assert.Equal(
 			t,
-			int64(ConsumerTimeout),
+			int64(DefaultHeartTimeout),
 			mockRepo.StoreCursorCursor.HeartTimeout,
 			"Did not store the correct HeartTimeout",
 		)
@@ -326,3 +331,239 @@ func TestMarkEntryProcessed(t *testing.T) {
 
 	})
 }
+
+func TestRelease(t *testing.T) {
+	t.Run("Release cursor", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{}
+
+		streamer := getTestStreamer(mockRepo, nil)
+		streamer.cursor.Name = "someName"
+
+		err := streamer.Release(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "someName", mockRepo.ReleaseCursorName, "Released wrong cursor")
+	})
+
+	t.Run("Nothing to release", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{}
+
+		streamer := getTestStreamer(mockRepo, nil)
+
+		err := streamer.Release(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, mockRepo.ReleaseCursorName, "Released a cursor")
+	})
+}
+
+func TestBeat(t *testing.T) {
+	t.Run("Cursor not stored yet", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{}
+
+		streamer := getTestStreamer(mockRepo, nil)
+		streamer.cursor.Name = "someName"
+
+		err := streamer.Beat(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, mockRepo.RefreshCursorCursor.Name, "Refreshed a cursor which is not stored")
+	})
+
+	t.Run("Refresh stored cursor", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{}
+		clock := mock.TestClock{Time: time.Now()}
+
+		streamer := getTestStreamer(mockRepo, clock)
+		streamer.HeartTimeout = time.Minute
+		streamer.cursor.Name = "someName"
+		streamer.cursor.Epoch = 2
+		streamer.streams = map[string]string{"1-0": "eventStream"}
+
+		err := streamer.MarkEntryProcessed(context.Background(), "1-0")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, int64(2), mockRepo.StoreCursorCursor.Epoch, "Stored wrong epoch")
+
+		err = streamer.Beat(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "someName", mockRepo.RefreshCursorCursor.Name, "Refreshed wrong cursor")
+		assert.Equal(t, int64(2), mockRepo.RefreshCursorCursor.Epoch, "Refreshed wrong epoch")
+		assert.Equal(t, int64(time.Minute), mockRepo.RefreshCursorCursor.HeartTimeout, "Refreshed with wrong timeout")
+	})
+
+	t.Run("Fenced out while beating", func(t *testing.T) {
+		fenced := &domain.FencedError{Name: "someName"}
+		mockRepo := &mock.TestRepo{
+			RefreshCursorReturnError: fenced,
+		}
+		clock := mock.TestClock{Time: time.Now()}
+
+		streamer := getTestStreamer(mockRepo, clock)
+		streamer.cursor.Name = "someName"
+		streamer.streams = map[string]string{"1-0": "eventStream", "2-0": "eventStream"}
+
+		err := streamer.MarkEntryProcessed(context.Background(), "1-0")
+		assert.Nil(t, err, "Error is not nil")
+
+		err = streamer.Beat(context.Background())
+		assert.Equal(t, fenced, err, "Error is not the domain.FencedError")
+
+		mockRepo.StoreCursorCursor = domain.StreamCursor{}
+		err = streamer.MarkEntryProcessed(context.Background(), "2-0")
+
+		assert.True(t, domain.IsFenced(err), "Error is not a domain.FencedError")
+		assert.Empty(t, mockRepo.StoreCursorCursor.Name, "Stored a fenced cursor")
+
+		err = streamer.Release(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, mockRepo.ReleaseCursorName, "Released a fenced cursor")
+	})
+}
+
+func TestFencing(t *testing.T) {
+	t.Run("Steal cursor with next epoch", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			GetCursorsReturnCursors: []domain.StreamCursor{
+				domain.StreamCursor{Name: "foo", Positions: map[string]string{"eventStream": "bar"}, Epoch: 4},
+			},
+			GetEntriesReturnEntries: []domain.Entry{domain.Entry{ID: "1-0", Stream: "eventStream"}},
+		}
+		clock := mock.TestClock{Time: time.Now()}
+
+		streamer := getTestStreamer(mockRepo, clock)
+
+		_, err := streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+
+		err = streamer.MarkEntryProcessed(context.Background(), "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, int64(5), mockRepo.StoreCursorCursor.Epoch, "Stored wrong epoch")
+	})
+
+	t.Run("Identify again after being fenced out", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			StoreCursorReturnError: &domain.FencedError{Name: "someName", Epoch: 1},
+		}
+		clock := mock.TestClock{Time: time.Now()}
+
+		streamer := getTestStreamer(mockRepo, clock)
+		streamer.cursor.Name = "someName"
+		streamer.cursor.Epoch = 1
+		streamer.streams = map[string]string{"1-0": "eventStream"}
+
+		err := streamer.MarkEntryProcessed(context.Background(), "1-0")
+		assert.True(t, domain.IsFenced(err), "Error is not a domain.FencedError")
+
+		_, err = streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.NotEqual(t, "someName", streamer.cursor.Name, "Kept the fenced name")
+		assert.Nil(t, mockRepo.GetEntriesPositions, "Did not start as a new consumer")
+		assert.Equal(t, int64(0), streamer.cursor.Epoch, "New consumer does not start at epoch 0")
+	})
+}
+
+func TestPositions(t *testing.T) {
+	t.Run("Track position per stream", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			GetEntriesReturnEntries: []domain.Entry{
+				domain.Entry{ID: "1-0", Stream: "events:1"},
+				domain.Entry{ID: "2-0", Stream: "events:2"},
+			},
+			GetEntriesReturnPositions: map[string]string{"events:1": "1-0", "events:2": "2-0"},
+		}
+		clock := mock.TestClock{Time: time.Now()}
+
+		streamer := getTestStreamer(mockRepo, clock)
+
+		_, err := streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+
+		err = streamer.MarkEntryProcessed(context.Background(), "1-0")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			map[string]string{"events:1": "1-0", "events:2": "1-18446744073709551615"},
+			mockRepo.StoreCursorCursor.Positions,
+			"Stored wrong positions",
+		)
+
+		err = streamer.MarkEntryProcessed(context.Background(), "2-0")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			map[string]string{"events:1": "1-0", "events:2": "2-0"},
+			mockRepo.StoreCursorCursor.Positions,
+			"Stored wrong positions",
+		)
+
+		_, err = streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Equal(t, mockRepo.GetEntriesReturnPositions, mockRepo.GetEntriesPositions, "Did not continue from read positions")
+	})
+
+	t.Run("Store the first read position of quiet streams", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			GetEntriesReturnEntries:   []domain.Entry{domain.Entry{ID: "8-0", Stream: "events:1"}},
+			GetEntriesReturnPositions: map[string]string{"events:1": "8-0", "events:2": "5-0"},
+		}
+
+		streamer := getTestStreamer(mockRepo, mock.TestClock{Time: time.Now()})
+
+		_, err := streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+
+		err = streamer.MarkEntryProcessed(context.Background(), "8-0")
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(
+			t,
+			map[string]string{"events:1": "8-0", "events:2": "5-0"},
+			mockRepo.StoreCursorCursor.Positions,
+			"Quiet stream not stored at its first read position",
+		)
+	})
+
+	t.Run("Entry not read by the streamer", func(t *testing.T) {
+		streamer := getTestStreamer(&mock.TestRepo{}, mock.TestClock{Time: time.Now()})
+
+		err := streamer.MarkEntryProcessed(context.Background(), "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestCountAttempt(t *testing.T) {
+	t.Run("Count attempt with the cursor", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			GetEntriesReturnEntries:   []domain.Entry{domain.Entry{ID: "1-0", Stream: "events:1"}},
+			GetEntriesReturnPositions: map[string]string{"events:1": "1-0"},
+			CountAttemptReturnCount:   2,
+		}
+
+		streamer := getTestStreamer(mockRepo, mock.TestClock{Time: time.Now()})
+
+		_, err := streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+
+		previous, err := streamer.CountAttempt(context.Background(), "1-0")
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, 2, previous, "Earlier attempts not correct")
+		assert.Equal(t, streamer.cursor.Name, mockRepo.CountAttemptName, "Counted for the wrong cursor")
+		assert.Equal(t, "events:1", mockRepo.CountAttemptStream, "Counted on the wrong stream")
+		assert.Equal(t, "1-0", mockRepo.CountAttemptID, "Counted the wrong entry")
+	})
+
+	t.Run("CountAttempt returns an error", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{CountAttemptReturnError: errors.New("some error")}
+
+		streamer := getTestStreamer(mockRepo, mock.TestClock{Time: time.Now()})
+
+		_, err := streamer.CountAttempt(context.Background(), "1-0")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
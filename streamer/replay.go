@@ -0,0 +1,55 @@
+package streamer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+)
+
+const (
+	//streamStartID is lower than the ID of any entry, reading after it reads the whole stream
+	streamStartID string = "0-0"
+)
+
+//StartFrom returns the position to read after so that reading starts with the entry with the given ID.
+//ID "0" starts at the beginning of the stream.
+func StartFrom(ID string) (string, error) {
+	millis, seq, err := domain.ParseID(ID)
+
+	if err != nil {
+		return "", fmt.Errorf("StartFrom: %s", err)
+	}
+
+	switch {
+	case seq > 0:
+		return fmt.Sprintf("%d-%d", millis, seq-1), nil
+	case millis > 0:
+		return fmt.Sprintf("%d-%d", millis-1, uint64(math.MaxUint64)), nil
+	default:
+		return streamStartID, nil
+	}
+}
+
+//StartTime returns the position to read after so that reading starts with the first entry added at or after t.
+func StartTime(t time.Time) string {
+	millis := t.UnixNano() / int64(time.Millisecond)
+
+	if millis <= 0 {
+		return streamStartID
+	}
+
+	return fmt.Sprintf("%d-%d", millis-1, uint64(math.MaxUint64))
+}
+
+//StartPositions returns the same start position for all the streams.
+func StartPositions(streams []string, position string) map[string]string {
+	positions := make(map[string]string, len(streams))
+
+	for _, stream := range streams {
+		positions[stream] = position
+	}
+
+	return positions
+}
@@ -0,0 +1,84 @@
+package streamer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartFrom(t *testing.T) {
+	cases := map[string]string{
+		"0":     "0-0",
+		"0-0":   "0-0",
+		"5-3":   "5-2",
+		"5-0":   "4-18446744073709551615",
+		"12345": "12344-18446744073709551615",
+	}
+
+	for ID, position := range cases {
+		t.Run(ID, func(t *testing.T) {
+			p, err := StartFrom(ID)
+
+			assert.Nil(t, err, "Error is not nil")
+			assert.Equal(t, position, p, "Position not correct")
+		})
+	}
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		_, err := StartFrom("yesterday")
+
+		assert.NotNil(t, err, "Error is nil")
+	})
+}
+
+func TestStartTime(t *testing.T) {
+	assert.Equal(t, "1543409999999-18446744073709551615", StartTime(time.Unix(1543410000, 0)), "Position not correct")
+	assert.Equal(t, "0-0", StartTime(time.Unix(0, 0)), "Position not correct")
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("Start at the start positions instead of stealing", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			GetCursorsReturnCursors: []domain.StreamCursor{
+				domain.StreamCursor{Name: "foo", Positions: map[string]string{"eventStream": "9-0"}},
+			},
+		}
+
+		streamer := getTestStreamer(mockRepo, mock.TestClock{Time: time.Now()})
+		streamer.Start = StartPositions([]string{"eventStream"}, "0-0")
+
+		_, err := streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Equal(t, map[string]string{"eventStream": "0-0"}, mockRepo.GetEntriesPositions, "Did not start at the start position")
+		assert.Empty(t, mockRepo.StealCursorNewName, "Stole a cursor")
+		assert.Equal(t, int64(0), streamer.cursor.Epoch, "Replay does not start at epoch 0")
+	})
+
+	t.Run("Start positions are used once", func(t *testing.T) {
+		mockRepo := &mock.TestRepo{
+			StoreCursorReturnError:  &domain.FencedError{Name: "someName", Epoch: 0},
+			GetEntriesReturnEntries: []domain.Entry{domain.Entry{ID: "1-0", Stream: "eventStream"}},
+		}
+
+		streamer := getTestStreamer(mockRepo, mock.TestClock{Time: time.Now()})
+		streamer.Start = StartPositions([]string{"eventStream"}, "0-0")
+
+		_, err := streamer.GetEntries(context.Background())
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+
+		err = streamer.MarkEntryProcessed(context.Background(), "1-0")
+		assert.True(t, domain.IsFenced(err), "Error is not a domain.FencedError")
+
+		mockRepo.GetEntriesPositions = nil
+
+		_, err = streamer.GetEntries(context.Background())
+
+		assert.Nil(t, err, "GetEntries returned non-nil error")
+		assert.Nil(t, mockRepo.GetEntriesPositions, "Replayed again after being fenced out")
+	})
+}
@@ -0,0 +1,193 @@
+package streamer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/metrics"
+)
+
+const (
+	//DefaultTrimInterval is the time between two trim passes when none is specified
+	DefaultTrimInterval time.Duration = 1 * time.Minute
+)
+
+//Trimmer removes entries older than the maximum age from the streams.
+type Trimmer struct {
+	Repo  domain.RetentionRepository
+	Clock Clock
+
+	//Streams are the names of the trimmed streams.
+	Streams []string
+	//MaxAge is the age after which entries are removed, nothing is removed when 0.
+	MaxAge time.Duration
+	//Safe keeps the entries not yet processed by a live cursor consumer, however old they are.
+	Safe bool
+	//Interval is the time between two trim passes.
+	Interval time.Duration
+
+	//Metrics count the trimmed entries when set.
+	Metrics *metrics.Registry
+}
+
+//Run trims the streams on every interval until the context is done.
+func (t Trimmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		trimmed, err := t.Trim(ctx)
+
+		for stream, count := range trimmed {
+			if count > 0 {
+				log.Printf("Trimmed %d entries from stream %s\n", count, stream)
+			}
+
+			if t.Metrics != nil {
+				t.Metrics.Counter("grs_entries_trimmed_total", "Entries removed by retention", "stream").Add(float64(count), stream)
+			}
+		}
+
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+}
+
+//Trim does a single pass over the streams and removes the entries older than the maximum age.
+//Entries held by the archiver are always kept, see HoldEntries.
+//In safe mode the entries after the lowest position of the live cursors are kept.
+//Returns the number of removed entries by stream, also when the pass is interrupted by an error.
+func (t Trimmer) Trim(ctx context.Context) (map[string]int64, error) {
+	trimmed := make(map[string]int64, len(t.Streams))
+
+	if t.MaxAge <= 0 {
+		return trimmed, nil
+	}
+
+	var cursors []domain.StreamCursor
+	var err error
+
+	if t.Safe {
+		cursors, err = t.Repo.GetCursors(ctx)
+
+		if err != nil {
+			return trimmed, fmt.Errorf("Trim: %s", err.Error())
+		}
+	}
+
+	cutoff := t.Clock.Now().Add(-t.MaxAge).UnixNano() / int64(time.Millisecond)
+
+	if cutoff <= 0 {
+		return trimmed, nil
+	}
+
+	//The greatest ID an entry added before the cutoff can have.
+	oldestKept := fmt.Sprintf("%d-%d", cutoff-1, uint64(math.MaxUint64))
+
+	for _, stream := range t.Streams {
+		maxID, ok := safeMaxID(cursors, stream, oldestKept)
+
+		if !ok {
+			log.Printf("Not trimming stream %s, a cursor position could not be parsed\n", stream)
+			continue
+		}
+
+		holds, err := t.Repo.GetHolds(ctx, stream)
+
+		if err != nil {
+			return trimmed, fmt.Errorf("Trim: %s", err.Error())
+		}
+
+		maxID, ok = heldMaxID(holds, maxID)
+
+		if !ok {
+			log.Printf("Not trimming stream %s, a hold could not be parsed\n", stream)
+			continue
+		}
+
+		if maxID == "" {
+			continue
+		}
+
+		count, err := t.Repo.TrimEntries(ctx, stream, maxID)
+		trimmed[stream] = count
+
+		if err != nil {
+			return trimmed, fmt.Errorf("Trim: %s", err.Error())
+		}
+	}
+
+	return trimmed, nil
+}
+
+func (t Trimmer) interval() time.Duration {
+	if t.Interval <= 0 {
+		return DefaultTrimInterval
+	}
+
+	return t.Interval
+}
+
+//safeMaxID lowers maxID to the lowest position on the stream of the live cursors.
+//Cursors without a position on the stream only read entries added after they started.
+//Returns false if a position can not be compared.
+func safeMaxID(cursors []domain.StreamCursor, stream, maxID string) (string, bool) {
+	for _, c := range cursors {
+		position, ok := c.Positions[stream]
+
+		if !c.HasHeart || !ok {
+			continue
+		}
+
+		cmp, err := domain.CompareIDs(position, maxID)
+
+		if err != nil {
+			return "", false
+		}
+
+		if cmp < 0 {
+			maxID = position
+		}
+	}
+
+	return maxID, true
+}
+
+//heldMaxID lowers maxID below the IDs of the holds on the stream, the held entries are kept.
+//Returns an empty maxID if no entry can be removed and false if a hold can not be parsed.
+func heldMaxID(holds map[string]string, maxID string) (string, bool) {
+	for _, ID := range holds {
+		if _, _, err := domain.ParseID(ID); err != nil {
+			return "", false
+		}
+
+		previous := domain.PreviousID(ID)
+
+		if previous == "" {
+			return "", true
+		}
+
+		cmp, err := domain.CompareIDs(previous, maxID)
+
+		if err != nil {
+			return "", false
+		}
+
+		if cmp < 0 {
+			maxID = previous
+		}
+	}
+
+	return maxID, true
+}
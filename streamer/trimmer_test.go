@@ -0,0 +1,173 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antekresic/grs/domain"
+	"github.com/antekresic/grs/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrim(t *testing.T) {
+	clock := mock.TestClock{Time: time.Unix(3600, 0)}
+
+	t.Run("Trim entries older than the maximum age", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			TrimEntriesReturnTrimmed: map[string]int64{"first": 3, "second": 0},
+		}
+
+		trimmer := Trimmer{
+			Repo:    mockRepo,
+			Clock:   clock,
+			Streams: []string{"first", "second"},
+			MaxAge:  time.Hour - time.Second,
+		}
+
+		trimmed, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, map[string]int64{"first": 3, "second": 0}, trimmed, "Trimmed counts not correct")
+		assert.Equal(t, "999-18446744073709551615", mockRepo.TrimEntriesMaxIDs["first"], "Max ID not correct")
+		assert.Equal(t, "999-18446744073709551615", mockRepo.TrimEntriesMaxIDs["second"], "Max ID not correct")
+		assert.False(t, mockRepo.GetCursorsCalled, "Cursors fetched outside safe mode")
+	})
+
+	t.Run("Safe mode keeps entries not processed by live cursors", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetCursorsReturnCursors: []domain.StreamCursor{
+				domain.StreamCursor{Name: "slow", HasHeart: true, Positions: map[string]string{"first": "500-1"}},
+				domain.StreamCursor{Name: "fast", HasHeart: true, Positions: map[string]string{"first": "2000-0", "second": "2000-0"}},
+				domain.StreamCursor{Name: "dead", Positions: map[string]string{"second": "1-0"}},
+			},
+		}
+
+		trimmer := Trimmer{
+			Repo:    mockRepo,
+			Clock:   clock,
+			Streams: []string{"first", "second"},
+			MaxAge:  time.Hour - time.Second,
+			Safe:    true,
+		}
+
+		_, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "500-1", mockRepo.TrimEntriesMaxIDs["first"], "Max ID not limited by the slow cursor")
+		assert.Equal(t, "999-18446744073709551615", mockRepo.TrimEntriesMaxIDs["second"], "Max ID limited by a dead cursor")
+	})
+
+	t.Run("Holds keep the entries from their IDs onwards", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetHoldsReturnHolds: map[string]map[string]string{
+				"first":  map[string]string{"archiver": "500-0", "backup": "2000-0"},
+				"second": map[string]string{"archiver": "0-0"},
+				"third":  map[string]string{"archiver": "2000-0"},
+			},
+		}
+
+		trimmer := Trimmer{
+			Repo:    mockRepo,
+			Clock:   clock,
+			Streams: []string{"first", "second", "third"},
+			MaxAge:  time.Hour - time.Second,
+		}
+
+		_, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Equal(t, "499-18446744073709551615", mockRepo.TrimEntriesMaxIDs["first"], "Max ID not limited by the lowest hold")
+		assert.NotContains(t, mockRepo.TrimEntriesMaxIDs, "second", "Stream held from the start trimmed")
+		assert.Equal(t, "999-18446744073709551615", mockRepo.TrimEntriesMaxIDs["third"], "Max ID limited by a later hold")
+	})
+
+	t.Run("Skip streams with unparsable holds", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetHoldsReturnHolds: map[string]map[string]string{"first": map[string]string{"archiver": "bad"}},
+		}
+
+		trimmer := Trimmer{Repo: mockRepo, Clock: clock, Streams: []string{"first"}, MaxAge: time.Minute}
+
+		trimmed, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, trimmed, "Stream trimmed")
+		assert.Empty(t, mockRepo.TrimEntriesMaxIDs, "Stream trimmed")
+	})
+
+	t.Run("Holds error", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetHoldsReturnError: errors.New("some error"),
+		}
+
+		trimmer := Trimmer{Repo: mockRepo, Clock: clock, Streams: []string{"first"}, MaxAge: time.Minute}
+
+		_, err := trimmer.Trim(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, mockRepo.TrimEntriesMaxIDs, "Stream trimmed")
+	})
+
+	t.Run("Safe mode skips streams with unparsable positions", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetCursorsReturnCursors: []domain.StreamCursor{
+				domain.StreamCursor{Name: "odd", HasHeart: true, Positions: map[string]string{"first": "$"}},
+			},
+		}
+
+		trimmer := Trimmer{
+			Repo:    mockRepo,
+			Clock:   clock,
+			Streams: []string{"first"},
+			MaxAge:  time.Minute,
+			Safe:    true,
+		}
+
+		trimmed, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, trimmed, "Stream trimmed")
+		assert.Empty(t, mockRepo.TrimEntriesMaxIDs, "Stream trimmed")
+	})
+
+	t.Run("No maximum age", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{}
+
+		trimmer := Trimmer{Repo: mockRepo, Clock: clock, Streams: []string{"first"}}
+
+		trimmed, err := trimmer.Trim(context.Background())
+
+		assert.Nil(t, err, "Error is not nil")
+		assert.Empty(t, trimmed, "Stream trimmed")
+		assert.Empty(t, mockRepo.TrimEntriesMaxIDs, "Stream trimmed")
+	})
+
+	t.Run("Cursors error", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			GetCursorsReturnError: errors.New("some error"),
+		}
+
+		trimmer := Trimmer{Repo: mockRepo, Clock: clock, Streams: []string{"first"}, MaxAge: time.Minute, Safe: true}
+
+		_, err := trimmer.Trim(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Empty(t, mockRepo.TrimEntriesMaxIDs, "Stream trimmed")
+	})
+
+	t.Run("Trim error", func(t *testing.T) {
+		mockRepo := &mock.TestRetentionRepo{
+			TrimEntriesReturnTrimmed: map[string]int64{"first": 2},
+			TrimEntriesReturnError:   errors.New("some error"),
+		}
+
+		trimmer := Trimmer{Repo: mockRepo, Clock: clock, Streams: []string{"first", "second"}, MaxAge: time.Minute}
+
+		trimmed, err := trimmer.Trim(context.Background())
+
+		assert.NotNil(t, err, "Error is nil")
+		assert.Equal(t, map[string]int64{"first": 2}, trimmed, "Trimmed counts not correct")
+	})
+}